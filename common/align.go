@@ -0,0 +1,69 @@
+package common
+
+import (
+	"sync"
+	"time"
+)
+
+// TruncateToInterval 将t向下对齐到d的整数倍，以Unix纪元(UTC)为起点，d<=0时原样返回
+func TruncateToInterval(t time.Time, d time.Duration) time.Time {
+	if d <= 0 {
+		return t
+	}
+	ns := t.UnixNano()
+	rem := ns % int64(d)
+	if rem < 0 {
+		rem += int64(d)
+	}
+	return time.Unix(0, ns-rem).In(t.Location())
+}
+
+// NextBoundary 返回严格晚于t的下一个d整数倍时刻
+func NextBoundary(t time.Time, d time.Duration) time.Time {
+	if d <= 0 {
+		return t
+	}
+	return TruncateToInterval(t, d).Add(d)
+}
+
+// AlignedTicker 在墙钟的d整数倍时刻触发，如d为1分钟时在每分钟整点触发。
+// 与time.Ticker一样，接收方处理不及时时丢弃中间的触发
+type AlignedTicker struct {
+	C <-chan time.Time
+
+	stopCh chan struct{}
+	once   sync.Once
+}
+
+func NewAlignedTicker(d time.Duration) *AlignedTicker {
+	if d <= 0 {
+		panic("non-positive interval for NewAlignedTicker")
+	}
+	c := make(chan time.Time, 1)
+	at := &AlignedTicker{C: c, stopCh: make(chan struct{})}
+	go at.run(d, c)
+	return at
+}
+
+func (at *AlignedTicker) run(d time.Duration, c chan<- time.Time) {
+	// 每次按当前时间重新计算下一个整点，避免timer误差累积
+	timer := time.NewTimer(time.Until(NextBoundary(time.Now(), d)))
+	defer timer.Stop()
+	for {
+		select {
+		case <-at.stopCh:
+			return
+		case now := <-timer.C:
+			select {
+			case c <- now:
+			default:
+			}
+			timer.Reset(time.Until(NextBoundary(now, d)))
+		}
+	}
+}
+
+// Stop 停止触发，不会关闭C
+func (at *AlignedTicker) Stop() {
+	at.once.Do(func() { close(at.stopCh) })
+}
@@ -0,0 +1,41 @@
+package common
+
+import (
+	"testing"
+	"time"
+)
+
+func TestTruncateToInterval(t *testing.T) {
+	ts := time.Date(2024, 5, 1, 10, 17, 42, 5, time.UTC)
+	if got := TruncateToInterval(ts, 5*time.Minute); !got.Equal(time.Date(2024, 5, 1, 10, 15, 0, 0, time.UTC)) {
+		t.Fatalf("truncate %v", got)
+	}
+	if got := NextBoundary(ts, time.Hour); !got.Equal(time.Date(2024, 5, 1, 11, 0, 0, 0, time.UTC)) {
+		t.Fatalf("next %v", got)
+	}
+	// 恰好在整点上时取下一个
+	on := time.Date(2024, 5, 1, 11, 0, 0, 0, time.UTC)
+	if got := NextBoundary(on, time.Hour); !got.Equal(on.Add(time.Hour)) {
+		t.Fatalf("next on boundary %v", got)
+	}
+	// 纪元之前的时间同样向下对齐
+	before := time.Unix(-90, 0)
+	if got := TruncateToInterval(before, time.Minute); got.Unix() != -120 {
+		t.Fatalf("before epoch %v", got.Unix())
+	}
+}
+
+func TestAlignedTicker(t *testing.T) {
+	at := NewAlignedTicker(50 * time.Millisecond)
+	defer at.Stop()
+	for i := 0; i < 3; i++ {
+		select {
+		case now := <-at.C:
+			if off := now.Sub(TruncateToInterval(now, 50*time.Millisecond)); off > 25*time.Millisecond {
+				t.Fatalf("tick %v is %v past the boundary", now, off)
+			}
+		case <-time.After(time.Second):
+			t.Fatal("no tick")
+		}
+	}
+}
@@ -0,0 +1,108 @@
+package common
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+)
+
+var (
+	ErrSequenceGap = errors.New("event sequence gap")
+)
+
+type ApplierOption[S, E any] func(*Applier[S, E])
+
+// WithSnapshotEvery 每应用n个事件调用一次hook保存快照，n为0或hook为nil时不生效
+func WithSnapshotEvery[S, E any](n uint64, hook func(seq uint64, state S) error) ApplierOption[S, E] {
+	return func(a *Applier[S, E]) {
+		if n > 0 && hook != nil {
+			a.snapshotEvery = n
+			a.onSnapshot = hook
+		}
+	}
+}
+
+// WithStrictSequence 要求序号连续，出现跳号时返回ErrSequenceGap（kafka offset可能因compaction跳号，默认不要求）
+func WithStrictSequence[S, E any]() ApplierOption[S, E] {
+	return func(a *Applier[S, E]) {
+		a.strict = true
+	}
+}
+
+// Applier 将带序号的事件依次应用到状态上，已应用过的序号直接跳过，保证重复投递时幂等
+type Applier[S, E any] struct {
+	mu            sync.Mutex
+	state         S
+	seq           uint64 // 最后应用的序号
+	started       bool   // 是否已应用过事件或从快照恢复
+	apply         func(S, E) (S, error)
+	strict        bool
+	snapshotEvery uint64
+	sinceSnapshot uint64
+	onSnapshot    func(seq uint64, state S) error
+}
+
+func NewApplier[S, E any](initial S, apply func(S, E) (S, error), opts ...ApplierOption[S, E]) *Applier[S, E] {
+	a := &Applier[S, E]{
+		state: initial,
+		apply: apply,
+	}
+	for _, opt := range opts {
+		opt(a)
+	}
+	return a
+}
+
+// RestoreApplier 从快照恢复，序号不大于seq的事件会被跳过
+func RestoreApplier[S, E any](snapshot S, seq uint64, apply func(S, E) (S, error), opts ...ApplierOption[S, E]) *Applier[S, E] {
+	a := NewApplier(snapshot, apply, opts...)
+	a.seq, a.started = seq, true
+	return a
+}
+
+// Apply 应用序号为seq的事件，已应用过时返回applied=false；apply失败时状态和序号不变
+func (a *Applier[S, E]) Apply(seq uint64, e E) (applied bool, err error) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	if a.started && seq <= a.seq {
+		return false, nil
+	}
+	if a.strict && a.started && seq != a.seq+1 {
+		return false, fmt.Errorf("%w: expect %d, got %d", ErrSequenceGap, a.seq+1, seq)
+	}
+
+	state, err := a.apply(a.state, e)
+	if err != nil {
+		return false, err
+	}
+	a.state, a.seq, a.started = state, seq, true
+
+	if a.snapshotEvery > 0 {
+		if a.sinceSnapshot++; a.sinceSnapshot >= a.snapshotEvery {
+			a.sinceSnapshot = 0
+			if err := a.onSnapshot(a.seq, a.state); err != nil {
+				return true, fmt.Errorf("snapshot at %d: %w", a.seq, err)
+			}
+		}
+	}
+	return true, nil
+}
+
+// State 返回当前状态及最后应用的序号
+func (a *Applier[S, E]) State() (S, uint64) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	return a.state, a.seq
+}
+
+// Snapshot 立即调用快照hook，未设置hook时不做任何事
+func (a *Applier[S, E]) Snapshot() error {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if a.onSnapshot == nil {
+		return nil
+	}
+	a.sinceSnapshot = 0
+	return a.onSnapshot(a.seq, a.state)
+}
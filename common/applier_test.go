@@ -0,0 +1,88 @@
+package common
+
+import (
+	"errors"
+	"testing"
+)
+
+func sumApply(s int, e int) (int, error) {
+	if e < 0 {
+		return s, errors.New("negative event")
+	}
+	return s + e, nil
+}
+
+func TestApplierSkipReplay(t *testing.T) {
+	a := RestoreApplier(10, 5, sumApply)
+	if applied, err := a.Apply(5, 1); applied || err != nil {
+		t.Fatalf("replayed seq applied: %v %v", applied, err)
+	}
+	if applied, err := a.Apply(6, 1); !applied || err != nil {
+		t.Fatalf("new seq not applied: %v %v", applied, err)
+	}
+	if applied, _ := a.Apply(6, 1); applied {
+		t.Fatal("duplicate seq applied")
+	}
+	if s, seq := a.State(); s != 11 || seq != 6 {
+		t.Fatalf("got %d@%d", s, seq)
+	}
+}
+
+func TestApplierStrictGap(t *testing.T) {
+	a := NewApplier(0, sumApply, WithStrictSequence[int, int]())
+	if _, err := a.Apply(1, 1); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := a.Apply(3, 1); !errors.Is(err, ErrSequenceGap) {
+		t.Fatalf("got %v", err)
+	}
+	if s, seq := a.State(); s != 1 || seq != 1 {
+		t.Fatalf("gap changed state: %d@%d", s, seq)
+	}
+
+	loose := NewApplier(0, sumApply)
+	if _, err := loose.Apply(1, 1); err != nil {
+		t.Fatal(err)
+	}
+	if applied, err := loose.Apply(3, 1); !applied || err != nil {
+		t.Fatalf("gap rejected without strict: %v %v", applied, err)
+	}
+}
+
+func TestApplierApplyError(t *testing.T) {
+	a := NewApplier(0, sumApply)
+	a.Apply(1, 2)
+	if applied, err := a.Apply(2, -1); applied || err == nil {
+		t.Fatalf("got %v %v", applied, err)
+	}
+	if s, seq := a.State(); s != 2 || seq != 1 {
+		t.Fatalf("failed apply changed state: %d@%d", s, seq)
+	}
+	if applied, _ := a.Apply(2, 3); !applied {
+		t.Fatal("seq should be retryable after failure")
+	}
+}
+
+func TestApplierSnapshotEvery(t *testing.T) {
+	var snaps []uint64
+	a := NewApplier(0, sumApply, WithSnapshotEvery[int, int](2, func(seq uint64, _ int) error {
+		snaps = append(snaps, seq)
+		return nil
+	}))
+	for seq := uint64(1); seq <= 5; seq++ {
+		if _, err := a.Apply(seq, 1); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if len(snaps) != 2 || snaps[0] != 2 || snaps[1] != 4 {
+		t.Fatalf("got %v", snaps)
+	}
+
+	nilHook := NewApplier(0, sumApply, WithSnapshotEvery[int, int](1, nil))
+	if _, err := nilHook.Apply(1, 1); err != nil {
+		t.Fatal(err)
+	}
+	if err := nilHook.Snapshot(); err != nil {
+		t.Fatal(err)
+	}
+}
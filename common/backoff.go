@@ -0,0 +1,80 @@
+package common
+
+import (
+	"math"
+	"time"
+)
+
+const (
+	BACKOFFFACTOR = 2 // 每次失败后等待时间的倍数, default
+)
+
+// BackoffJitter Backoff的随机化方式
+type BackoffJitter int
+
+const (
+	JitterNone  BackoffJitter = iota // 不随机
+	JitterFull                       // [0, d]
+	JitterEqual                      // [d/2, d]
+)
+
+// Backoff 指数退避计算器，第n次Next返回base*factor^n，不超过max；
+// 非并发安全，每个重试循环持有自己的实例
+type Backoff struct {
+	base    time.Duration
+	max     time.Duration
+	factor  float64
+	jitter  BackoffJitter
+	rand    Rand
+	attempt int
+}
+
+// NewBackoff base为首次等待时间，max为上限，max<base时按base处理
+func NewBackoff(base, max time.Duration) *Backoff {
+	return &Backoff{
+		base:   base,
+		max:    Max(base, max),
+		factor: BACKOFFFACTOR,
+		rand:   DefaultRand,
+	}
+}
+
+// WithFactor 设置倍数，factor<1时按1处理即固定间隔
+func (b *Backoff) WithFactor(factor float64) *Backoff {
+	b.factor = Max(factor, 1)
+	return b
+}
+
+// WithJitter 对每次等待时间随机化，避免多个实例同时重试；r为nil时使用DefaultRand
+func (b *Backoff) WithJitter(jitter BackoffJitter, r Rand) *Backoff {
+	b.jitter = jitter
+	if r != nil {
+		b.rand = r
+	}
+	return b
+}
+
+// Next 返回本次应等待的时间并推进到下一次
+func (b *Backoff) Next() time.Duration {
+	d := b.max
+	if exp := float64(b.base) * math.Pow(b.factor, float64(b.attempt)); exp < float64(b.max) {
+		d = time.Duration(exp)
+	}
+	if d < b.max {
+		b.attempt++
+	}
+
+	switch b.jitter {
+	case JitterFull:
+		return RandDuration(b.rand, d+1)
+	case JitterEqual:
+		return d/2 + RandDuration(b.rand, d-d/2+1)
+	default:
+		return d
+	}
+}
+
+// Reset 成功后调用，下一次Next重新从base开始
+func (b *Backoff) Reset() {
+	b.attempt = 0
+}
@@ -0,0 +1,28 @@
+package common
+
+import (
+	"testing"
+	"time"
+)
+
+func TestBackoff(t *testing.T) {
+	b := NewBackoff(100*time.Millisecond, time.Second)
+	want := []time.Duration{100, 200, 400, 800, 1000, 1000}
+	for i, w := range want {
+		if got := b.Next(); got != w*time.Millisecond {
+			t.Fatalf("attempt %d: got %v, want %v", i, got, w*time.Millisecond)
+		}
+	}
+	b.Reset()
+	if got := b.Next(); got != 100*time.Millisecond {
+		t.Fatalf("after reset: got %v", got)
+	}
+
+	eq := NewBackoff(time.Second, time.Minute).WithJitter(JitterEqual, NewSeededRand(1))
+	for i := 0; i < 5; i++ {
+		base := time.Second << i
+		if got := eq.Next(); got < base/2 || got > base {
+			t.Fatalf("equal jitter attempt %d: %v outside [%v, %v]", i, got, base/2, base)
+		}
+	}
+}
@@ -0,0 +1,54 @@
+package common
+
+import (
+	"context"
+	"time"
+)
+
+type budgetCtxKey struct{}
+
+// Budget 多步骤处理共享的时间预算，各步骤按剩余时间的比例切分截止时间
+type Budget struct {
+	ctx      context.Context
+	deadline time.Time
+}
+
+// WithBudget 创建总时长为total的预算，ctx已有更早的截止时间时以ctx为准
+func WithBudget(ctx context.Context, total time.Duration) (*Budget, context.CancelFunc) {
+	ctx, cancel := context.WithTimeout(ctx, total)
+	deadline, _ := ctx.Deadline()
+	b := &Budget{deadline: deadline}
+	b.ctx = context.WithValue(ctx, budgetCtxKey{}, b)
+	return b, cancel
+}
+
+// BudgetFromContext 取出WithBudget或SubBudget的ctx中携带的预算
+func BudgetFromContext(ctx context.Context) (*Budget, bool) {
+	b, ok := ctx.Value(budgetCtxKey{}).(*Budget)
+	return b, ok
+}
+
+func (b *Budget) Context() context.Context {
+	return b.ctx
+}
+
+func (b *Budget) Deadline() time.Time {
+	return b.deadline
+}
+
+// Remaining 剩余时间，已超时返回0
+func (b *Budget) Remaining() time.Duration {
+	return Max(time.Until(b.deadline), 0)
+}
+
+func (b *Budget) Expired() bool {
+	return b.Remaining() == 0
+}
+
+// SubBudget 将剩余时间的fraction分配给下一步，子预算截止时间不会晚于整体截止时间，
+// 返回的子预算ctx也可再次切分
+func (b *Budget) SubBudget(fraction float64) (*Budget, context.CancelFunc) {
+	fraction = Min(Max(fraction, 0), 1)
+	d := time.Duration(float64(b.Remaining()) * fraction)
+	return WithBudget(b.ctx, d)
+}
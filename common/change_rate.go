@@ -0,0 +1,118 @@
+package common
+
+import (
+	"sync"
+	"time"
+
+	"golang.org/x/exp/constraints"
+)
+
+// ChangeRateEvent 变化率持续超过阈值时触发
+type ChangeRateEvent struct {
+	Rate      float64       // 当前每秒变化量
+	Threshold float64       // 触发的阈值
+	Duration  time.Duration // 已持续超过阈值的时间
+	At        time.Time
+}
+
+type changeRateThreshold struct {
+	rate    float64
+	sustain time.Duration
+	fn      func(ChangeRateEvent)
+
+	since time.Time // 开始超过阈值的时间，零值表示未超过
+	fired bool      // 本轮超过阈值期间是否已触发
+}
+
+// exceeded rate为正时表示上升速度超过阈值，为负时表示下降速度超过阈值
+func (th *changeRateThreshold) exceeded(rate float64) bool {
+	if th.rate >= 0 {
+		return rate > th.rate
+	}
+	return rate < th.rate
+}
+
+type rateSample[T constraints.Integer | constraints.Float] struct {
+	at    time.Time
+	value T
+}
+
+// ChangeRateMonitor 跟踪数值序列(如lag、队列长度、错误数)，按window内首尾样本计算每秒变化率，
+// 变化率持续超过阈值sustain时间后触发回调，每轮超过阈值只触发一次。
+// 与TriggerWindow的计数告警互补，用于趋势告警
+type ChangeRateMonitor[T constraints.Integer | constraints.Float] struct {
+	mu         sync.Mutex
+	window     time.Duration
+	samples    []rateSample[T]
+	thresholds []*changeRateThreshold
+}
+
+func NewChangeRateMonitor[T constraints.Integer | constraints.Float](window time.Duration) *ChangeRateMonitor[T] {
+	return &ChangeRateMonitor[T]{window: window}
+}
+
+// OnRate 注册阈值：rate为每秒变化量，正数监控上升、负数监控下降
+func (m *ChangeRateMonitor[T]) OnRate(rate float64, sustain time.Duration, fn func(ChangeRateEvent)) *ChangeRateMonitor[T] {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.thresholds = append(m.thresholds, &changeRateThreshold{rate: rate, sustain: sustain, fn: fn})
+	return m
+}
+
+// Observe 记录一个样本并检查阈值，回调在调用方goroutine中同步执行
+func (m *ChangeRateMonitor[T]) Observe(value T) {
+	m.observe(time.Now(), value)
+}
+
+func (m *ChangeRateMonitor[T]) observe(now time.Time, value T) {
+	m.mu.Lock()
+	m.samples = append(m.samples, rateSample[T]{at: now, value: value})
+	expired := 0
+	// 至少保留两个样本，保证稀疏上报时仍能计算变化率
+	for expired < len(m.samples)-2 && now.Sub(m.samples[expired].at) > m.window {
+		expired++
+	}
+	m.samples = m.samples[expired:]
+
+	rate := m.rate()
+	var events []func()
+	for _, th := range m.thresholds {
+		if !th.exceeded(rate) {
+			th.since, th.fired = time.Time{}, false
+			continue
+		}
+		if th.since.IsZero() {
+			th.since = now
+		}
+		if !th.fired && now.Sub(th.since) >= th.sustain {
+			th.fired = true
+			ev := ChangeRateEvent{Rate: rate, Threshold: th.rate, Duration: now.Sub(th.since), At: now}
+			fn := th.fn
+			events = append(events, func() { fn(ev) })
+		}
+	}
+	m.mu.Unlock()
+
+	for _, f := range events {
+		f()
+	}
+}
+
+// Rate 当前每秒变化率，样本不足两个时返回0
+func (m *ChangeRateMonitor[T]) Rate() float64 {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.rate()
+}
+
+func (m *ChangeRateMonitor[T]) rate() float64 {
+	if len(m.samples) < 2 {
+		return 0
+	}
+	first, last := m.samples[0], m.samples[len(m.samples)-1]
+	elapsed := last.at.Sub(first.at).Seconds()
+	if elapsed <= 0 {
+		return 0
+	}
+	return (float64(last.value) - float64(first.value)) / elapsed
+}
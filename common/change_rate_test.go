@@ -0,0 +1,32 @@
+package common
+
+import (
+	"testing"
+	"time"
+)
+
+func TestChangeRateMonitor(t *testing.T) {
+	var fired []ChangeRateEvent
+	m := NewChangeRateMonitor[int](10*time.Second).
+		OnRate(5, 3*time.Second, func(ev ChangeRateEvent) { fired = append(fired, ev) })
+
+	start := time.Now()
+	// 每秒上升10，持续超过阈值3秒后只触发一次
+	for i := 0; i <= 6; i++ {
+		m.observe(start.Add(time.Duration(i)*time.Second), i*10)
+	}
+	if len(fired) != 1 || fired[0].Rate != 10 {
+		t.Fatalf("fired %+v", fired)
+	}
+
+	// 回落后重新超过阈值会再次触发
+	for i := 7; i <= 20; i++ {
+		m.observe(start.Add(time.Duration(i)*time.Second), 60)
+	}
+	for i := 21; i <= 25; i++ {
+		m.observe(start.Add(time.Duration(i)*time.Second), 60+(i-20)*100)
+	}
+	if len(fired) != 2 {
+		t.Fatalf("fired %d times, want 2", len(fired))
+	}
+}
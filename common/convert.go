@@ -0,0 +1,150 @@
+package common
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"unsafe"
+
+	"golang.org/x/exp/constraints"
+)
+
+var (
+	ErrOutOfRange = errors.New("value out of range")
+)
+
+// SafeConvert 整数类型转换，v超出To的范围时返回ErrOutOfRange而不是静默截断。
+// To在前以便From由参数推导，如SafeConvert[int32](offset)
+func SafeConvert[To, From constraints.Integer](v From) (To, error) {
+	r := To(v)
+	if From(r) != v || (v < 0) != (r < 0) {
+		return 0, fmt.Errorf("%w: %v does not fit in %T", ErrOutOfRange, v, r)
+	}
+	return r, nil
+}
+
+// SaturatingConvert 整数类型转换，超出To的范围时取To的最大或最小值
+func SaturatingConvert[To, From constraints.Integer](v From) To {
+	r, err := SafeConvert[To](v)
+	if err == nil {
+		return r
+	}
+	if v < 0 {
+		return minOf[To]()
+	}
+	return maxOf[To]()
+}
+
+func isSigned[T constraints.Integer]() bool {
+	var zero T
+	return zero-1 < 0
+}
+
+func maxOf[T constraints.Integer]() T {
+	bits := unsafe.Sizeof(T(0)) * 8
+	if isSigned[T]() {
+		return T(1)<<(bits-1) - 1
+	}
+	return ^T(0)
+}
+
+func minOf[T constraints.Integer]() T {
+	if isSigned[T]() {
+		return -maxOf[T]() - 1
+	}
+	return 0
+}
+
+// SafeIntToInt32 超出int32范围时ok为false
+func SafeIntToInt32(v int) (int32, bool) {
+	r, err := SafeConvert[int32](v)
+	return r, err == nil
+}
+
+func SafeInt64ToInt32(v int64) (int32, bool) {
+	r, err := SafeConvert[int32](v)
+	return r, err == nil
+}
+
+// SafeInt64ToInt 在32位平台上检查范围
+func SafeInt64ToInt(v int64) (int, bool) {
+	r, err := SafeConvert[int](v)
+	return r, err == nil
+}
+
+func SafeIntToUint32(v int) (uint32, bool) {
+	r, err := SafeConvert[uint32](v)
+	return r, err == nil
+}
+
+func SafeInt64ToUint64(v int64) (uint64, bool) {
+	r, err := SafeConvert[uint64](v)
+	return r, err == nil
+}
+
+func SafeUint64ToInt64(v uint64) (int64, bool) {
+	r, err := SafeConvert[int64](v)
+	return r, err == nil
+}
+
+func SafeUint64ToInt(v uint64) (int, bool) {
+	r, err := SafeConvert[int](v)
+	return r, err == nil
+}
+
+// SafeFloat64ToInt64 截断小数部分，NaN或超出int64范围时ok为false
+func SafeFloat64ToInt64(v float64) (int64, bool) {
+	// float64(math.MaxInt64)会进位到2^63，需用<比较
+	if math.IsNaN(v) || v < math.MinInt64 || v >= math.MaxInt64 {
+		return 0, false
+	}
+	return int64(v), true
+}
+
+func MustIntToInt32(v int) int32 {
+	r, ok := SafeIntToInt32(v)
+	return mustConvert(r, ok, v)
+}
+
+func MustInt64ToInt32(v int64) int32 {
+	r, ok := SafeInt64ToInt32(v)
+	return mustConvert(r, ok, v)
+}
+
+func MustInt64ToInt(v int64) int {
+	r, ok := SafeInt64ToInt(v)
+	return mustConvert(r, ok, v)
+}
+
+func MustIntToUint32(v int) uint32 {
+	r, ok := SafeIntToUint32(v)
+	return mustConvert(r, ok, v)
+}
+
+func MustInt64ToUint64(v int64) uint64 {
+	r, ok := SafeInt64ToUint64(v)
+	return mustConvert(r, ok, v)
+}
+
+func MustUint64ToInt64(v uint64) int64 {
+	r, ok := SafeUint64ToInt64(v)
+	return mustConvert(r, ok, v)
+}
+
+func MustUint64ToInt(v uint64) int {
+	r, ok := SafeUint64ToInt(v)
+	return mustConvert(r, ok, v)
+}
+
+func MustFloat64ToInt64(v float64) int64 {
+	r, ok := SafeFloat64ToInt64(v)
+	return mustConvert(r, ok, v)
+}
+
+// mustConvert 转换失败时panic，错误信息包含原值和目标类型
+func mustConvert[T any](r T, ok bool, v any) T {
+	if !ok {
+		panic(fmt.Errorf("%w: %v does not fit in %T", ErrOutOfRange, v, r))
+	}
+	return r
+}
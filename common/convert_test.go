@@ -0,0 +1,53 @@
+package common
+
+import (
+	"errors"
+	"math"
+	"testing"
+)
+
+func TestSafeConvert(t *testing.T) {
+	if _, ok := SafeIntToInt32(math.MaxInt32 + 1); ok {
+		t.Fatal("int32 overflow not detected")
+	}
+	if v, ok := SafeInt64ToInt32(math.MinInt32); !ok || v != math.MinInt32 {
+		t.Fatalf("got %d %v", v, ok)
+	}
+	if _, ok := SafeUint64ToInt64(math.MaxUint64); ok {
+		t.Fatal("int64 overflow not detected")
+	}
+	if _, ok := SafeInt64ToUint64(-1); ok {
+		t.Fatal("negative value not detected")
+	}
+	if _, ok := SafeFloat64ToInt64(math.Pow(2, 63)); ok {
+		t.Fatal("float overflow not detected")
+	}
+	if _, ok := SafeFloat64ToInt64(math.NaN()); ok {
+		t.Fatal("NaN not detected")
+	}
+
+	defer func() {
+		if recover() == nil {
+			t.Fatal("MustIntToUint32 did not panic")
+		}
+	}()
+	MustIntToUint32(-1)
+}
+
+func TestSaturatingConvert(t *testing.T) {
+	if v := SaturatingConvert[int8](300); v != math.MaxInt8 {
+		t.Fatalf("got %d", v)
+	}
+	if v := SaturatingConvert[int8](-300); v != math.MinInt8 {
+		t.Fatalf("got %d", v)
+	}
+	if v := SaturatingConvert[uint16](-1); v != 0 {
+		t.Fatalf("got %d", v)
+	}
+	if v := SaturatingConvert[uint32](uint64(math.MaxUint64)); v != math.MaxUint32 {
+		t.Fatalf("got %d", v)
+	}
+	if _, err := SafeConvert[uint](int64(-1)); !errors.Is(err, ErrOutOfRange) {
+		t.Fatalf("got %v", err)
+	}
+}
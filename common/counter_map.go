@@ -0,0 +1,73 @@
+package common
+
+import (
+	"time"
+)
+
+// CounterMap 按key计数，每次Incr的计数在ttl后过期
+type CounterMap[K comparable] struct {
+	shards SyncMapGroup[K, []time.Time]
+}
+
+// NewCounterMap shards需为2的幂
+func NewCounterMap[K comparable](shards, capacity int) *CounterMap[K] {
+	return &CounterMap[K]{
+		shards: NewSyncMapGroup[K, []time.Time](shards, capacity/shards+1),
+	}
+}
+
+// Incr 计数加一并返回当前未过期的计数
+func (cm *CounterMap[K]) Incr(k K, ttl time.Duration) int {
+	now := time.Now()
+	expires, _ := cm.shards.ShardFor(k).Compute(k, func(old []time.Time, _ bool) ([]time.Time, bool) {
+		return append(pruneExpired(old, now), now.Add(ttl)), true
+	})
+	return len(expires)
+}
+
+func (cm *CounterMap[K]) Count(k K) int {
+	now := time.Now()
+	expires, _ := cm.shards.ShardFor(k).Compute(k, func(old []time.Time, _ bool) ([]time.Time, bool) {
+		old = pruneExpired(old, now)
+		return old, len(old) > 0
+	})
+	return len(expires)
+}
+
+func (cm *CounterMap[K]) Reset(k K) {
+	cm.shards.ShardFor(k).Delete(k)
+}
+
+// Sweep 清理计数已全部过期的key，可由使用方定期调用
+func (cm *CounterMap[K]) Sweep() {
+	now := time.Now()
+	for _, shard := range cm.shards {
+		var idle []K
+		shard.Range(func(k K, expires []time.Time) bool {
+			for _, t := range expires {
+				if t.After(now) {
+					return true
+				}
+			}
+			idle = append(idle, k)
+			return true
+		})
+		for _, k := range idle {
+			shard.Compute(k, func(old []time.Time, _ bool) ([]time.Time, bool) {
+				old = pruneExpired(old, now)
+				return old, len(old) > 0
+			})
+		}
+	}
+}
+
+// pruneExpired 原地过滤已过期的时间点
+func pruneExpired(expires []time.Time, now time.Time) []time.Time {
+	valid := expires[:0]
+	for _, t := range expires {
+		if t.After(now) {
+			valid = append(valid, t)
+		}
+	}
+	return valid
+}
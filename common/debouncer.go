@@ -0,0 +1,71 @@
+package common
+
+import (
+	"sync"
+	"time"
+)
+
+// Debouncer 尾沿执行：每次Call重新计时，静默wait后只执行最后一次传入的f，用于合并突发的调用。
+// 与Pacer的首沿节流相反
+type Debouncer struct {
+	mu      sync.Mutex
+	wait    time.Duration
+	timer   *time.Timer
+	pending func()
+	gen     uint64 // 每次Call递增，过期的定时器回调据此放弃执行
+}
+
+func NewDebouncer(wait time.Duration) *Debouncer {
+	return &Debouncer{wait: wait}
+}
+
+// Call 安排f在静默wait后执行，覆盖之前尚未执行的f
+func (d *Debouncer) Call(f func()) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	d.gen++
+	gen := d.gen
+	d.pending = f
+	if d.timer != nil {
+		d.timer.Stop()
+	}
+	d.timer = time.AfterFunc(d.wait, func() {
+		d.mu.Lock()
+		if d.gen != gen || d.pending == nil {
+			d.mu.Unlock()
+			return
+		}
+		f := d.pending
+		d.pending = nil
+		d.mu.Unlock()
+		f()
+	})
+}
+
+// Flush 立即在调用方goroutine中执行尚未执行的f，返回是否执行
+func (d *Debouncer) Flush() bool {
+	f := d.take()
+	if f == nil {
+		return false
+	}
+	f()
+	return true
+}
+
+// Stop 取消尚未执行的f
+func (d *Debouncer) Stop() {
+	d.take()
+}
+
+func (d *Debouncer) take() func() {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if d.timer != nil {
+		d.timer.Stop()
+	}
+	d.gen++
+	f := d.pending
+	d.pending = nil
+	return f
+}
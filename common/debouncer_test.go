@@ -0,0 +1,31 @@
+package common
+
+import (
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestDebouncer(t *testing.T) {
+	d := NewDebouncer(20 * time.Millisecond)
+	var last, runs atomic.Int64
+	for i := 1; i <= 5; i++ {
+		d.Call(func() {
+			last.Store(int64(i))
+			runs.Add(1)
+		})
+	}
+	time.Sleep(50 * time.Millisecond)
+	if runs.Load() != 1 || last.Load() != 5 {
+		t.Fatalf("runs %d last %d", runs.Load(), last.Load())
+	}
+
+	d.Call(func() { runs.Add(1) })
+	if !d.Flush() || runs.Load() != 2 {
+		t.Fatal("flush did not run pending call")
+	}
+	time.Sleep(30 * time.Millisecond)
+	if runs.Load() != 2 {
+		t.Fatal("flushed call ran twice")
+	}
+}
@@ -0,0 +1,217 @@
+package common
+
+import (
+	"errors"
+	"fmt"
+	"math/big"
+	"strconv"
+	"strings"
+)
+
+const (
+	MAXDECIMALSCALE = 18 // int64最多容纳18位小数
+)
+
+var (
+	ErrInvalidDecimal = errors.New("invalid decimal")
+)
+
+// RoundingMode Decimal降低精度时的舍入方式
+type RoundingMode int
+
+const (
+	RoundHalfUp   RoundingMode = iota // 四舍五入，.5远离0
+	RoundHalfEven                     // 银行家舍入，.5取偶
+	RoundDown                         // 向0截断
+	RoundUp                           // 远离0
+	RoundFloor                        // 向负无穷
+	RoundCeil                         // 向正无穷
+)
+
+// Decimal 定点小数，值为units/10^scale，用于价格、数量等需要精确十进制运算的场景。
+// 运算溢出时返回ErrOutOfRange而不是静默截断
+type Decimal struct {
+	units int64
+	scale uint8
+}
+
+// NewDecimal units/10^scale，如NewDecimal(12345, 2)表示123.45
+func NewDecimal(units int64, scale int) Decimal {
+	if scale < 0 || scale > MAXDECIMALSCALE {
+		panic(fmt.Sprintf("decimal scale %d out of range", scale))
+	}
+	return Decimal{units: units, scale: uint8(scale)}
+}
+
+// ParseDecimal 解析"-123.45"形式的字符串，精度取小数位数
+func ParseDecimal(s string) (Decimal, error) {
+	str := s
+	neg := strings.HasPrefix(str, "-")
+	str = strings.TrimPrefix(strings.TrimPrefix(str, "-"), "+")
+	intPart, fracPart, _ := strings.Cut(str, ".")
+	if intPart == "" && fracPart == "" || len(fracPart) > MAXDECIMALSCALE ||
+		strings.Trim(intPart+fracPart, "0123456789") != "" {
+		return Decimal{}, fmt.Errorf("%w: %q", ErrInvalidDecimal, s)
+	}
+	digits := strings.TrimLeft(intPart+fracPart, "0")
+	if digits == "" {
+		digits = "0"
+	}
+	if neg {
+		digits = "-" + digits
+	}
+	units, err := strconv.ParseInt(digits, 10, 64)
+	if err != nil {
+		return Decimal{}, fmt.Errorf("%w: %q", ErrOutOfRange, s)
+	}
+	return Decimal{units: units, scale: uint8(len(fracPart))}, nil
+}
+
+// MustParseDecimal 同ParseDecimal，失败时panic，用于常量
+func MustParseDecimal(s string) Decimal {
+	d, err := ParseDecimal(s)
+	if err != nil {
+		panic(err)
+	}
+	return d
+}
+
+func (d Decimal) Units() int64 { return d.units }
+func (d Decimal) Scale() int   { return int(d.scale) }
+func (d Decimal) IsZero() bool { return d.units == 0 }
+
+func (d Decimal) String() string {
+	if d.scale == 0 {
+		return strconv.FormatInt(d.units, 10)
+	}
+	digits := strconv.FormatInt(d.units, 10)
+	sign := ""
+	if d.units < 0 {
+		sign, digits = "-", digits[1:]
+	}
+	if pad := int(d.scale) + 1 - len(digits); pad > 0 {
+		digits = strings.Repeat("0", pad) + digits
+	}
+	cut := len(digits) - int(d.scale)
+	return sign + digits[:cut] + "." + digits[cut:]
+}
+
+// Float64 转换为float64，可能损失精度，仅用于展示或近似计算
+func (d Decimal) Float64() float64 {
+	f, _ := strconv.ParseFloat(d.String(), 64)
+	return f
+}
+
+// Cmp 返回-1、0、1
+func (d Decimal) Cmp(o Decimal) int {
+	a, b := d.bigAligned(o)
+	return a.Cmp(b)
+}
+
+// Add 结果精度取两者较大者
+func (d Decimal) Add(o Decimal) (Decimal, error) {
+	a, b := d.bigAligned(o)
+	return fromBig(a.Add(a, b), Max(d.scale, o.scale))
+}
+
+func (d Decimal) Sub(o Decimal) (Decimal, error) {
+	a, b := d.bigAligned(o)
+	return fromBig(a.Sub(a, b), Max(d.scale, o.scale))
+}
+
+// Mul 乘积按mode舍入到scale位小数
+func (d Decimal) Mul(o Decimal, scale int, mode RoundingMode) (Decimal, error) {
+	p := new(big.Int).Mul(big.NewInt(d.units), big.NewInt(o.units))
+	return rescaleBig(p, int(d.scale)+int(o.scale), scale, mode)
+}
+
+// Div 商按mode舍入到scale位小数
+func (d Decimal) Div(o Decimal, scale int, mode RoundingMode) (Decimal, error) {
+	if o.units == 0 {
+		return Decimal{}, fmt.Errorf("%w: division by zero", ErrInvalidDecimal)
+	}
+	if scale < 0 || scale > MAXDECIMALSCALE {
+		return Decimal{}, fmt.Errorf("%w: scale %d", ErrInvalidDecimal, scale)
+	}
+	// d/o = d.units*10^(scale+o.scale) / (o.units*10^d.scale) / 10^scale
+	num := new(big.Int).Mul(big.NewInt(d.units), pow10(scale+int(o.scale)))
+	den := new(big.Int).Mul(big.NewInt(o.units), pow10(int(d.scale)))
+	if den.Sign() < 0 {
+		num.Neg(num)
+		den.Neg(den)
+	}
+	return fromBig(roundQuo(num, den, mode), uint8(scale))
+}
+
+// Rescale 调整到scale位小数，降低精度时按mode舍入
+func (d Decimal) Rescale(scale int, mode RoundingMode) (Decimal, error) {
+	return rescaleBig(big.NewInt(d.units), int(d.scale), scale, mode)
+}
+
+func (d Decimal) Neg() Decimal {
+	return Decimal{units: -d.units, scale: d.scale}
+}
+
+// bigAligned 将两者对齐到较大的精度
+func (d Decimal) bigAligned(o Decimal) (*big.Int, *big.Int) {
+	a, b := big.NewInt(d.units), big.NewInt(o.units)
+	if d.scale < o.scale {
+		a.Mul(a, pow10(int(o.scale-d.scale)))
+	} else if o.scale < d.scale {
+		b.Mul(b, pow10(int(d.scale-o.scale)))
+	}
+	return a, b
+}
+
+func rescaleBig(v *big.Int, from, to int, mode RoundingMode) (Decimal, error) {
+	if to < 0 || to > MAXDECIMALSCALE {
+		return Decimal{}, fmt.Errorf("%w: scale %d", ErrInvalidDecimal, to)
+	}
+	if to >= from {
+		return fromBig(v.Mul(v, pow10(to-from)), uint8(to))
+	}
+	return fromBig(roundQuo(v, pow10(from-to), mode), uint8(to))
+}
+
+// roundQuo 计算v/div并按mode舍入，div为正
+func roundQuo(v, div *big.Int, mode RoundingMode) *big.Int {
+	q, r := new(big.Int).QuoRem(v, div, new(big.Int))
+	if r.Sign() == 0 {
+		return q
+	}
+	sign := int64(v.Sign())
+	half := new(big.Int).Abs(r)
+	half.Mul(half, big.NewInt(2))
+	cmpHalf := half.Cmp(div)
+
+	var away bool
+	switch mode {
+	case RoundHalfUp:
+		away = cmpHalf >= 0
+	case RoundHalfEven:
+		away = cmpHalf > 0 || cmpHalf == 0 && q.Bit(0) == 1
+	case RoundDown:
+		away = false
+	case RoundUp:
+		away = true
+	case RoundFloor:
+		away = sign < 0
+	case RoundCeil:
+		away = sign > 0
+	}
+	if away {
+		q.Add(q, big.NewInt(sign))
+	}
+	return q
+}
+
+func fromBig(v *big.Int, scale uint8) (Decimal, error) {
+	if !v.IsInt64() {
+		return Decimal{}, fmt.Errorf("%w: %s does not fit in decimal", ErrOutOfRange, v)
+	}
+	return Decimal{units: v.Int64(), scale: scale}, nil
+}
+
+func pow10(n int) *big.Int {
+	return new(big.Int).Exp(big.NewInt(10), big.NewInt(int64(n)), nil)
+}
@@ -0,0 +1,49 @@
+package common
+
+import (
+	"testing"
+)
+
+func TestDecimal(t *testing.T) {
+	a := MustParseDecimal("123.45")
+	b := MustParseDecimal("-0.005")
+	if a.String() != "123.45" || b.String() != "-0.005" {
+		t.Fatalf("format %s %s", a, b)
+	}
+
+	sum, err := a.Add(b)
+	if err != nil || sum.String() != "123.445" {
+		t.Fatalf("add %s %v", sum, err)
+	}
+	if r, _ := sum.Rescale(2, RoundHalfUp); r.String() != "123.45" {
+		t.Fatalf("half up %s", r)
+	}
+	if r, _ := sum.Rescale(2, RoundHalfEven); r.String() != "123.44" {
+		t.Fatalf("half even %s", r)
+	}
+	if r, _ := b.Rescale(2, RoundFloor); r.String() != "-0.01" {
+		t.Fatalf("floor %s", r)
+	}
+	if r, _ := b.Rescale(2, RoundDown); r.String() != "0.00" {
+		t.Fatalf("down %s", r)
+	}
+
+	p, err := MustParseDecimal("0.1").Mul(MustParseDecimal("3"), 2, RoundHalfUp)
+	if err != nil || p.String() != "0.30" {
+		t.Fatalf("mul %s %v", p, err)
+	}
+	q, err := MustParseDecimal("1").Div(MustParseDecimal("-3"), 4, RoundHalfUp)
+	if err != nil || q.String() != "-0.3333" {
+		t.Fatalf("div %s %v", q, err)
+	}
+	if MustParseDecimal("1.10").Cmp(MustParseDecimal("1.1")) != 0 {
+		t.Fatal("cmp across scales")
+	}
+
+	if _, err := ParseDecimal("1.2.3"); err == nil {
+		t.Fatal("invalid decimal parsed")
+	}
+	if _, err := NewDecimal(1<<62, 0).Add(NewDecimal(1<<62, 0)); err == nil {
+		t.Fatal("overflow not detected")
+	}
+}
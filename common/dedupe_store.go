@@ -0,0 +1,378 @@
+package common
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"math"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	DEDUPEEXPECTEDKEYS  = 100000
+	DEDUPEFALSEPOSITIVE = 0.001
+	DEDUPEFLUSHINTERVAL = time.Second
+
+	dedupeSegmentSuffix = ".seg"
+)
+
+var (
+	ErrInvalidDedupeTTL = errors.New("dedupe ttl must be positive")
+)
+
+type DedupeMode int
+
+const (
+	DedupeExact  DedupeMode = iota // 每段保存完整key集合
+	DedupeApprox                   // 每段使用bloom filter，有少量误判为已见过
+)
+
+type DedupeConfig struct {
+	TTL             time.Duration // key的保留时长
+	Mode            DedupeMode
+	Dir             string        // 非空时将key追加写入磁盘分段，重启后恢复
+	SegmentDuration time.Duration // 每个分段覆盖的时间, default TTL/4
+	ExpectedKeys    int           // 近似模式下每段预期的key数量, default DEDUPEEXPECTEDKEYS
+	FalsePositive   float64       // 近似模式下的误判率, default DEDUPEFALSEPOSITIVE
+	FlushInterval   time.Duration // 后台将写入缓冲刷到文件的间隔，进程崩溃时最多丢失这段时间内的key, default DEDUPEFLUSHINTERVAL
+}
+
+type dedupeSegment struct {
+	start time.Time
+	keys  map[string]struct{}
+	bloom *bloomFilter
+	file  *os.File
+	w     *bufio.Writer
+}
+
+func (s *dedupeSegment) contains(key string) bool {
+	if s.bloom != nil {
+		return s.bloom.contains(key)
+	}
+	_, ok := s.keys[key]
+	return ok
+}
+
+func (s *dedupeSegment) add(key string) {
+	if s.bloom != nil {
+		s.bloom.add(key)
+	} else {
+		s.keys[key] = struct{}{}
+	}
+}
+
+// DedupeStore 按时间分段滚动的去重存储，key在TTL到TTL+SegmentDuration之间过期
+type DedupeStore struct {
+	mu       sync.Mutex
+	cfg      DedupeConfig
+	segments []*dedupeSegment // 按start升序，最后一个为当前写入段
+	flushed  time.Time        // 上次将写入缓冲刷到文件的时间
+
+	stopCh   chan struct{}
+	stopOnce sync.Once
+	wg       sync.WaitGroup
+}
+
+func OpenDedupeStore(cfg DedupeConfig) (*DedupeStore, error) {
+	if cfg.TTL <= 0 {
+		return nil, ErrInvalidDedupeTTL
+	}
+	if cfg.SegmentDuration <= 0 {
+		cfg.SegmentDuration = cfg.TTL / 4
+	}
+	if cfg.ExpectedKeys <= 0 {
+		cfg.ExpectedKeys = DEDUPEEXPECTEDKEYS
+	}
+	if cfg.FalsePositive <= 0 || cfg.FalsePositive >= 1 {
+		cfg.FalsePositive = DEDUPEFALSEPOSITIVE
+	}
+	if cfg.FlushInterval <= 0 {
+		cfg.FlushInterval = DEDUPEFLUSHINTERVAL
+	}
+
+	ds := &DedupeStore{cfg: cfg}
+	if cfg.Dir != "" {
+		if err := os.MkdirAll(cfg.Dir, 0o755); err != nil {
+			return nil, err
+		}
+		if err := ds.load(time.Now()); err != nil {
+			ds.Close()
+			return nil, err
+		}
+		ds.stopCh = make(chan struct{})
+		ds.wg.Add(1)
+		go ds.flushLoop()
+	}
+	return ds, nil
+}
+
+// flushLoop 定期刷写入缓冲，避免写入停顿时key长时间留在内存中
+func (ds *DedupeStore) flushLoop() {
+	defer ds.wg.Done()
+	ticker := time.NewTicker(ds.cfg.FlushInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ds.stopCh:
+			return
+		case <-ticker.C:
+			if err := ds.Flush(); err != nil {
+				log.Printf("dedupe store flush to %s failed: %v", ds.cfg.Dir, err)
+			}
+		}
+	}
+}
+
+// SeenRecently 返回key是否在TTL内出现过，并记录本次出现
+func (ds *DedupeStore) SeenRecently(key string) (bool, error) {
+	ds.mu.Lock()
+	defer ds.mu.Unlock()
+
+	now := time.Now()
+	if err := ds.rotate(now); err != nil {
+		return false, err
+	}
+	for _, seg := range ds.segments {
+		if seg.contains(key) {
+			return true, nil
+		}
+	}
+
+	cur := ds.segments[len(ds.segments)-1]
+	cur.add(key)
+	if cur.w != nil {
+		if err := writeDedupeKey(cur.w, key); err != nil {
+			return false, err
+		}
+		if now.Sub(ds.flushed) >= ds.cfg.FlushInterval {
+			if err := ds.flush(now); err != nil {
+				return false, err
+			}
+		}
+	}
+	return false, nil
+}
+
+// Flush 将已记录的key写入文件，未设置Dir时什么也不做
+func (ds *DedupeStore) Flush() error {
+	ds.mu.Lock()
+	defer ds.mu.Unlock()
+	return ds.flush(time.Now())
+}
+
+func (ds *DedupeStore) flush(now time.Time) error {
+	ds.flushed = now
+	if n := len(ds.segments); n > 0 && ds.segments[n-1].w != nil {
+		return ds.segments[n-1].w.Flush()
+	}
+	return nil
+}
+
+func (ds *DedupeStore) Close() (err error) {
+	if ds.stopCh != nil {
+		ds.stopOnce.Do(func() { close(ds.stopCh) })
+		ds.wg.Wait()
+	}
+	ds.mu.Lock()
+	defer ds.mu.Unlock()
+	for _, seg := range ds.segments {
+		err = errors.Join(err, closeDedupeSegment(seg))
+	}
+	ds.segments = nil
+	return
+}
+
+// rotate 丢弃过期分段，必要时创建新的写入段
+func (ds *DedupeStore) rotate(now time.Time) error {
+	expired := 0
+	for _, seg := range ds.segments {
+		if now.Sub(seg.start) < ds.cfg.TTL+ds.cfg.SegmentDuration {
+			break
+		}
+		expired++
+	}
+	for _, seg := range ds.segments[:expired] {
+		closeDedupeSegment(seg)
+		if seg.file != nil {
+			os.Remove(seg.file.Name())
+		}
+	}
+	ds.segments = ds.segments[expired:]
+
+	if n := len(ds.segments); n > 0 && now.Sub(ds.segments[n-1].start) < ds.cfg.SegmentDuration {
+		return nil
+	}
+	// 之后只向新的写入段追加
+	if err := ds.flush(now); err != nil {
+		return err
+	}
+	seg, err := ds.newSegment(now.Truncate(ds.cfg.SegmentDuration))
+	if err != nil {
+		return err
+	}
+	ds.segments = append(ds.segments, seg)
+	return nil
+}
+
+func (ds *DedupeStore) newSegment(start time.Time) (*dedupeSegment, error) {
+	seg := &dedupeSegment{start: start}
+	if ds.cfg.Mode == DedupeApprox {
+		seg.bloom = newBloomFilter(ds.cfg.ExpectedKeys, ds.cfg.FalsePositive)
+	} else {
+		seg.keys = make(map[string]struct{}, 1024)
+	}
+	if ds.cfg.Dir != "" {
+		path := filepath.Join(ds.cfg.Dir, strconv.FormatInt(start.UnixNano(), 10)+dedupeSegmentSuffix)
+		f, err := os.OpenFile(path, os.O_CREATE|os.O_APPEND|os.O_RDWR, 0o644)
+		if err != nil {
+			return nil, err
+		}
+		seg.file, seg.w = f, bufio.NewWriter(f)
+	}
+	return seg, nil
+}
+
+// load 从磁盘恢复未过期的分段，删除过期分段
+func (ds *DedupeStore) load(now time.Time) error {
+	entries, err := os.ReadDir(ds.cfg.Dir)
+	if err != nil {
+		return err
+	}
+
+	var starts []int64
+	for _, e := range entries {
+		name := e.Name()
+		if e.IsDir() || !strings.HasSuffix(name, dedupeSegmentSuffix) {
+			continue
+		}
+		ns, err := strconv.ParseInt(strings.TrimSuffix(name, dedupeSegmentSuffix), 10, 64)
+		if err != nil {
+			continue
+		}
+		if now.Sub(time.Unix(0, ns)) >= ds.cfg.TTL+ds.cfg.SegmentDuration {
+			os.Remove(filepath.Join(ds.cfg.Dir, name))
+			continue
+		}
+		starts = append(starts, ns)
+	}
+	sort.Slice(starts, func(i, j int) bool { return starts[i] < starts[j] })
+
+	for _, ns := range starts {
+		seg, err := ds.newSegment(time.Unix(0, ns))
+		if err != nil {
+			return err
+		}
+		ds.segments = append(ds.segments, seg)
+		if err := replayDedupeSegment(seg); err != nil {
+			return fmt.Errorf("replay %s: %w", seg.file.Name(), err)
+		}
+	}
+	return nil
+}
+
+func replayDedupeSegment(seg *dedupeSegment) error {
+	if _, err := seg.file.Seek(0, io.SeekStart); err != nil {
+		return err
+	}
+	r := bufio.NewReader(seg.file)
+	var offset int64
+	for {
+		key, n, err := readDedupeKey(r)
+		if err == io.EOF {
+			return nil
+		} else if err == io.ErrUnexpectedEOF {
+			// 末尾不完整的记录来自写入中途崩溃，截断后继续追加
+			return seg.file.Truncate(offset)
+		} else if err != nil {
+			return err
+		}
+		offset += n
+		seg.add(key)
+	}
+}
+
+func closeDedupeSegment(seg *dedupeSegment) error {
+	if seg.file == nil {
+		return nil
+	}
+	return errors.Join(seg.w.Flush(), seg.file.Close())
+}
+
+func writeDedupeKey(w *bufio.Writer, key string) error {
+	var buf [binary.MaxVarintLen64]byte
+	n := binary.PutUvarint(buf[:], uint64(len(key)))
+	if _, err := w.Write(buf[:n]); err != nil {
+		return err
+	}
+	_, err := w.WriteString(key)
+	return err
+}
+
+// readDedupeKey 返回key及其记录占用的字节数
+func readDedupeKey(r *bufio.Reader) (string, int64, error) {
+	n, err := binary.ReadUvarint(r)
+	if err != nil {
+		return "", 0, err
+	}
+	b := make([]byte, n)
+	if _, err := io.ReadFull(r, b); err != nil {
+		if err == io.EOF {
+			err = io.ErrUnexpectedEOF
+		}
+		return "", 0, err
+	}
+	var buf [binary.MaxVarintLen64]byte
+	return string(b), int64(binary.PutUvarint(buf[:], n)) + int64(n), nil
+}
+
+type bloomFilter struct {
+	bits []uint64
+	m    uint64
+	k    uint64
+}
+
+func newBloomFilter(n int, p float64) *bloomFilter {
+	m := uint64(math.Ceil(-float64(n) * math.Log(p) / (math.Ln2 * math.Ln2)))
+	k := uint64(math.Max(1, math.Round(float64(m)/float64(n)*math.Ln2)))
+	return &bloomFilter{
+		bits: make([]uint64, (m+63)/64),
+		m:    m,
+		k:    k,
+	}
+}
+
+// location 双重hash: h1 + i*h2
+func (bf *bloomFilter) location(h1, h2, i uint64) (word uint64, mask uint64) {
+	loc := (h1 + i*h2) % bf.m
+	return loc / 64, 1 << (loc % 64)
+}
+
+func (bf *bloomFilter) add(key string) {
+	h1 := hashString(key)
+	h2 := mix64(h1) | 1
+	for i := uint64(0); i < bf.k; i++ {
+		w, mask := bf.location(h1, h2, i)
+		bf.bits[w] |= mask
+	}
+}
+
+func (bf *bloomFilter) contains(key string) bool {
+	h1 := hashString(key)
+	h2 := mix64(h1) | 1
+	for i := uint64(0); i < bf.k; i++ {
+		w, mask := bf.location(h1, h2, i)
+		if bf.bits[w]&mask == 0 {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,48 @@
+package common
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"go.uber.org/multierr"
+)
+
+var (
+	ErrNoFallbackSource = errors.New("no fallback source")
+)
+
+// Fallback 依次用sources调用f，返回第一个成功的结果；timeout>0时限制每次尝试的时长，
+// 全部失败时返回聚合后的错误
+func Fallback[S, T any](ctx context.Context, sources []S, timeout time.Duration, f func(context.Context, S) (T, error)) (r T, err error) {
+	if len(sources) == 0 {
+		return r, ErrNoFallbackSource
+	}
+	for _, src := range sources {
+		if ctxErr := ctx.Err(); ctxErr != nil {
+			return r, multierr.Append(err, ctxErr)
+		}
+		v, e := fallbackAttempt(ctx, src, timeout, f)
+		if e == nil {
+			return v, nil
+		}
+		err = multierr.Append(err, e)
+	}
+	return r, err
+}
+
+// FallbackFuncs 依次尝试多个实现，语义同Fallback
+func FallbackFuncs[T any](ctx context.Context, timeout time.Duration, fns ...func(context.Context) (T, error)) (T, error) {
+	return Fallback(ctx, fns, timeout, func(ctx context.Context, f func(context.Context) (T, error)) (T, error) {
+		return f(ctx)
+	})
+}
+
+func fallbackAttempt[S, T any](ctx context.Context, src S, timeout time.Duration, f func(context.Context, S) (T, error)) (T, error) {
+	if timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
+	}
+	return f(ctx, src)
+}
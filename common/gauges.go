@@ -0,0 +1,106 @@
+package common
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"regexp"
+	"sync"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+var (
+	ErrGaugeExists      = errors.New("gauge already registered")
+	ErrInvalidGaugeName = errors.New("invalid gauge name")
+
+	gaugeNameRe = regexp.MustCompile(`^[a-zA-Z_:][a-zA-Z0-9_:]*$`)
+
+	// DefaultGauges 全局gauge注册表，需自行通过prometheus.MustRegister(DefaultGauges)导出
+	DefaultGauges = NewGauges("")
+)
+
+type gauge struct {
+	desc *prometheus.Desc
+	fn   func() float64
+}
+
+// Gauges 以名字注册func() float64，采集时才求值，同时实现prometheus.Collector
+type Gauges struct {
+	mu        *sync.RWMutex
+	namespace string
+	gauges    map[string]gauge
+}
+
+func NewGauges(namespace string) *Gauges {
+	return &Gauges{
+		mu:        &sync.RWMutex{},
+		namespace: namespace,
+		gauges:    make(map[string]gauge, 16),
+	}
+}
+
+func (g *Gauges) Register(name, help string, fn func() float64) error {
+	if !gaugeNameRe.MatchString(name) {
+		return fmt.Errorf("%w: %s", ErrInvalidGaugeName, name)
+	}
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if _, ok := g.gauges[name]; ok {
+		return fmt.Errorf("%w: %s", ErrGaugeExists, name)
+	}
+	g.gauges[name] = gauge{
+		desc: prometheus.NewDesc(prometheus.BuildFQName(g.namespace, "", name), help, nil, nil),
+		fn:   fn,
+	}
+	return nil
+}
+
+// GaugeFunc RegisterAll中的一个gauge
+type GaugeFunc struct {
+	Name string
+	Help string
+	Fn   func() float64
+}
+
+// RegisterAll 依次注册gauges，名字加上prefix，遇到错误时停止
+func (g *Gauges) RegisterAll(prefix string, gauges []GaugeFunc) error {
+	for _, gg := range gauges {
+		if err := g.Register(prefix+gg.Name, gg.Help, gg.Fn); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (g *Gauges) Unregister(name string) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	delete(g.gauges, name)
+}
+
+// Snapshot 对所有gauge求值
+func (g *Gauges) Snapshot() map[string]float64 {
+	g.mu.RLock()
+	defer g.mu.RUnlock()
+	r := make(map[string]float64, len(g.gauges))
+	for name, gg := range g.gauges {
+		r[name] = gg.fn()
+	}
+	return r
+}
+
+func (g *Gauges) MarshalJSON() ([]byte, error) {
+	return json.Marshal(g.Snapshot())
+}
+
+// Describe 不输出任何Desc，注册为unchecked collector以支持动态增删gauge
+func (g *Gauges) Describe(chan<- *prometheus.Desc) {}
+
+func (g *Gauges) Collect(ch chan<- prometheus.Metric) {
+	g.mu.RLock()
+	defer g.mu.RUnlock()
+	for _, gg := range g.gauges {
+		ch <- prometheus.MustNewConstMetric(gg.desc, prometheus.GaugeValue, gg.fn())
+	}
+}
@@ -0,0 +1,141 @@
+package common
+
+import (
+	"context"
+	"errors"
+	"io"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+)
+
+const (
+	HANDOFFPOLL = 100 * time.Millisecond
+)
+
+// Handoff 零停机重启时新旧进程间的状态交接：
+// 旧进程Register后调用WaitDrain，新进程启动时调用Takeover通知旧进程排空，
+// 旧进程将最小状态(offset、快照等)写入交接文件后退出，新进程读取该状态继续运行
+type Handoff struct {
+	Dir    string         // pid文件和状态文件所在目录
+	Name   string         // 服务名，用于区分同目录下的多个服务
+	Codec  Codec          // default GobCodec
+	Signal syscall.Signal // 通知旧进程排空的信号, default SIGTERM
+}
+
+func (h *Handoff) pidPath() string {
+	return filepath.Join(h.Dir, h.Name+".pid")
+}
+
+func (h *Handoff) statePath() string {
+	return filepath.Join(h.Dir, h.Name+".handoff")
+}
+
+func (h *Handoff) signal() syscall.Signal {
+	if h.Signal == 0 {
+		return syscall.SIGTERM
+	}
+	return h.Signal
+}
+
+// Register 将当前进程pid写入pid文件
+func (h *Handoff) Register() error {
+	return os.WriteFile(h.pidPath(), []byte(strconv.Itoa(os.Getpid())), 0o644)
+}
+
+// WaitDrain 阻塞直到收到排空信号或ctx取消；收到信号时调用drain获取状态并写入交接文件
+func (h *Handoff) WaitDrain(ctx context.Context, drain func() (any, error)) error {
+	ch := make(chan os.Signal, 1)
+	signal.Notify(ch, h.signal())
+	defer signal.Stop(ch)
+
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-ch:
+	}
+
+	state, err := drain()
+	if err != nil {
+		return err
+	}
+	return writeFileAtomic(h.statePath(), func(w io.Writer) error {
+		return h.codec().Encode(w, state)
+	})
+}
+
+// Takeover 通知旧进程排空并等待其写出状态，读取后登记当前进程pid。
+// 没有存活的旧进程时，只读取遗留的交接文件(如有)，found表示是否读到了状态
+func (h *Handoff) Takeover(ctx context.Context, state any) (found bool, err error) {
+	if proc := h.oldProcess(); proc != nil {
+		if err := proc.Signal(h.signal()); err == nil {
+			if err := h.waitState(ctx); err != nil {
+				return false, err
+			}
+		}
+	}
+
+	if found, err = h.readState(state); err != nil {
+		return
+	}
+	return found, h.Register()
+}
+
+func (h *Handoff) codec() Codec {
+	if h.Codec == nil {
+		return GobCodec
+	}
+	return h.Codec
+}
+
+// oldProcess 读取pid文件，进程不存在时返回nil
+func (h *Handoff) oldProcess() *os.Process {
+	b, err := os.ReadFile(h.pidPath())
+	if err != nil {
+		return nil
+	}
+	pid, err := strconv.Atoi(strings.TrimSpace(string(b)))
+	if err != nil || pid == os.Getpid() {
+		return nil
+	}
+	proc, err := os.FindProcess(pid)
+	if err != nil || proc.Signal(syscall.Signal(0)) != nil {
+		return nil
+	}
+	return proc
+}
+
+func (h *Handoff) waitState(ctx context.Context) error {
+	ticker := time.NewTicker(HANDOFFPOLL)
+	defer ticker.Stop()
+	for {
+		if _, err := os.Stat(h.statePath()); err == nil {
+			return nil
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
+
+// readState 读取并删除交接文件，保证状态只被消费一次
+func (h *Handoff) readState(state any) (bool, error) {
+	f, err := os.Open(h.statePath())
+	if errors.Is(err, os.ErrNotExist) {
+		return false, nil
+	} else if err != nil {
+		return false, err
+	}
+	defer os.Remove(h.statePath())
+	defer f.Close()
+	if err := h.codec().Decode(f, state); err != nil {
+		return false, err
+	}
+	return true, nil
+}
@@ -0,0 +1,65 @@
+package common
+
+import (
+	"hash/maphash"
+)
+
+const (
+	fnvOffset64 = 14695981039346656037
+	fnvPrime64  = 1099511628211
+)
+
+var hashSeed = maphash.MakeSeed()
+
+// HashKey 计算key的64位hash，string和内置整数类型的结果跨进程稳定，
+// 其他类型(包括以string/整数为底层类型的自定义类型)仅在进程内稳定
+func HashKey[K comparable](k K) uint64 {
+	switch v := any(k).(type) {
+	case string:
+		return hashString(v)
+	case int:
+		return mix64(uint64(v))
+	case int8:
+		return mix64(uint64(v))
+	case int16:
+		return mix64(uint64(v))
+	case int32:
+		return mix64(uint64(v))
+	case int64:
+		return mix64(uint64(v))
+	case uint:
+		return mix64(uint64(v))
+	case uint8:
+		return mix64(uint64(v))
+	case uint16:
+		return mix64(uint64(v))
+	case uint32:
+		return mix64(uint64(v))
+	case uint64:
+		return mix64(v)
+	case uintptr:
+		return mix64(uint64(v))
+	default:
+		return maphash.Comparable(hashSeed, k)
+	}
+}
+
+// hashString FNV-1a
+func hashString(s string) uint64 {
+	h := uint64(fnvOffset64)
+	for i := 0; i < len(s); i++ {
+		h ^= uint64(s[i])
+		h *= fnvPrime64
+	}
+	return h
+}
+
+// mix64 splitmix64 finalizer，打散连续整数
+func mix64(x uint64) uint64 {
+	x ^= x >> 30
+	x *= 0xbf58476d1ce4e5b9
+	x ^= x >> 27
+	x *= 0x94d049bb133111eb
+	x ^= x >> 31
+	return x
+}
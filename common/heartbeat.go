@@ -0,0 +1,92 @@
+package common
+
+import (
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+var (
+	// DefaultHeartbeat 全局心跳注册表，需自行通过prometheus.MustRegister(DefaultHeartbeat)导出
+	DefaultHeartbeat = NewHeartbeat("")
+
+	heartbeatLabels = []string{"name"}
+)
+
+// Heartbeat 后台循环定期调用Beat报告存活，按名字记录最后一次心跳时间，
+// 同时实现prometheus.Collector，导出每个名字距上次心跳的秒数
+type Heartbeat struct {
+	mu    *sync.RWMutex
+	beats map[string]time.Time
+	desc  *prometheus.Desc
+}
+
+func NewHeartbeat(namespace string) *Heartbeat {
+	return &Heartbeat{
+		mu:    &sync.RWMutex{},
+		beats: make(map[string]time.Time, 16),
+		desc: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, "", "heartbeat_age_seconds"),
+			"seconds since the last heartbeat of a background loop",
+			heartbeatLabels, nil),
+	}
+}
+
+// Beat 记录name的一次心跳
+func (h *Heartbeat) Beat(name string) {
+	now := time.Now()
+	h.mu.Lock()
+	h.beats[name] = now
+	h.mu.Unlock()
+}
+
+// LastBeat 返回name最后一次心跳时间，未心跳过时ok为false
+func (h *Heartbeat) LastBeat(name string) (t time.Time, ok bool) {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	t, ok = h.beats[name]
+	return
+}
+
+// Alive 距上次心跳不超过maxAge
+func (h *Heartbeat) Alive(name string, maxAge time.Duration) bool {
+	t, ok := h.LastBeat(name)
+	return ok && time.Since(t) <= maxAge
+}
+
+// Stale 返回超过maxAge未心跳的名字，按名字排序
+func (h *Heartbeat) Stale(maxAge time.Duration) []string {
+	now := time.Now()
+	h.mu.RLock()
+	var stale []string
+	for name, t := range h.beats {
+		if now.Sub(t) > maxAge {
+			stale = append(stale, name)
+		}
+	}
+	h.mu.RUnlock()
+	sort.Strings(stale)
+	return stale
+}
+
+// Remove 后台循环正常退出时移除，避免被判定为失活
+func (h *Heartbeat) Remove(name string) {
+	h.mu.Lock()
+	delete(h.beats, name)
+	h.mu.Unlock()
+}
+
+func (h *Heartbeat) Describe(ch chan<- *prometheus.Desc) {
+	ch <- h.desc
+}
+
+func (h *Heartbeat) Collect(ch chan<- prometheus.Metric) {
+	now := time.Now()
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	for name, t := range h.beats {
+		ch <- prometheus.MustNewConstMetric(h.desc, prometheus.GaugeValue, now.Sub(t).Seconds(), name)
+	}
+}
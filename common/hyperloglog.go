@@ -0,0 +1,94 @@
+package common
+
+import (
+	"errors"
+	"math"
+	"math/bits"
+)
+
+const (
+	HLLMINPRECISION = 4
+	HLLMAXPRECISION = 18
+)
+
+var (
+	ErrHLLPrecision         = errors.New("hyperloglog precision out of range")
+	ErrHLLPrecisionMismatch = errors.New("hyperloglog precision mismatch")
+)
+
+// HyperLogLog 基数估计，占用2^precision字节，标准误差约1.04/sqrt(2^precision)；非并发安全
+type HyperLogLog struct {
+	p    uint8
+	regs []uint8
+}
+
+func NewHyperLogLog(precision uint8) (*HyperLogLog, error) {
+	if precision < HLLMINPRECISION || precision > HLLMAXPRECISION {
+		return nil, ErrHLLPrecision
+	}
+	return &HyperLogLog{
+		p:    precision,
+		regs: make([]uint8, 1<<precision),
+	}, nil
+}
+
+func (h *HyperLogLog) AddString(s string) {
+	h.AddHash(mix64(hashString(s)))
+}
+
+// AddHash 添加已hash的值，hash需分布均匀(如HashKey的结果)
+func (h *HyperLogLog) AddHash(x uint64) {
+	idx := x >> (64 - h.p)
+	w := x<<h.p | 1<<(h.p-1) // 保证w非0，rho最大为64-p+1
+	if rho := uint8(bits.LeadingZeros64(w)) + 1; rho > h.regs[idx] {
+		h.regs[idx] = rho
+	}
+}
+
+func (h *HyperLogLog) Count() uint64 {
+	m := float64(len(h.regs))
+	sum, zeros := 0.0, 0
+	for _, r := range h.regs {
+		sum += math.Ldexp(1, -int(r))
+		if r == 0 {
+			zeros++
+		}
+	}
+
+	est := hllAlpha(len(h.regs)) * m * m / sum
+	if est <= 2.5*m && zeros > 0 {
+		// 小基数时使用线性计数修正
+		est = m * math.Log(m/float64(zeros))
+	}
+	return uint64(est + 0.5)
+}
+
+// Merge 合并other的统计结果，用于汇总多个窗口或分片
+func (h *HyperLogLog) Merge(other *HyperLogLog) error {
+	if h.p != other.p {
+		return ErrHLLPrecisionMismatch
+	}
+	for i, r := range other.regs {
+		if r > h.regs[i] {
+			h.regs[i] = r
+		}
+	}
+	return nil
+}
+
+func (h *HyperLogLog) Reset() {
+	clear(h.regs)
+}
+
+func hllAlpha(m int) float64 {
+	switch m {
+	case 16:
+		return 0.673
+	case 32:
+		return 0.697
+	case 64:
+		return 0.709
+	default:
+		return 0.7213 / (1 + 1.079/float64(m))
+	}
+}
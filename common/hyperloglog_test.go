@@ -0,0 +1,25 @@
+package common
+
+import (
+	"strconv"
+	"testing"
+)
+
+func TestHyperLogLog(t *testing.T) {
+	a, _ := NewHyperLogLog(14)
+	b, _ := NewHyperLogLog(14)
+	for i := 0; i < 60000; i++ {
+		a.AddString(strconv.Itoa(i))
+	}
+	for i := 40000; i < 100000; i++ {
+		b.AddString(strconv.Itoa(i))
+	}
+	if err := a.Merge(b); err != nil {
+		t.Fatal(err)
+	}
+
+	got := float64(a.Count())
+	if ratio := got / 100000; ratio < 0.97 || ratio > 1.03 {
+		t.Fatalf("estimate %v too far from 100000", got)
+	}
+}
@@ -0,0 +1,93 @@
+package common
+
+import (
+	"context"
+	"errors"
+	"time"
+)
+
+var (
+	ErrBucketFull = errors.New("leaky bucket full")
+)
+
+// LeakyBucket 按key的漏桶，将突发的调用排队后以固定间隔依次放行。
+// 与Pacer直接丢弃多余调用不同，Submit会阻塞到轮到自己为止
+type LeakyBucket[K comparable] struct {
+	shards   SyncMapGroup[K, time.Time] // 每个key下一个可放行的时间
+	interval time.Duration
+	capacity int
+}
+
+// NewLeakyBucket 每个key每interval放行一次，最多capacity个调用排队；shards需为2的幂
+func NewLeakyBucket[K comparable](shards int, interval time.Duration, capacity int) *LeakyBucket[K] {
+	return &LeakyBucket[K]{
+		shards:   NewSyncMapGroup[K, time.Time](shards, 64),
+		interval: interval,
+		capacity: capacity,
+	}
+}
+
+// Submit 阻塞到key的下一个放行时间；排队已满时立即返回ErrBucketFull。
+// ctx先结束时返回ctx.Err()，已分配的放行时间不再归还
+func (lb *LeakyBucket[K]) Submit(ctx context.Context, key K) error {
+	now := time.Now()
+	var (
+		at   time.Time
+		full bool
+	)
+	lb.shards.ShardFor(key).Compute(key, func(next time.Time, _ bool) (time.Time, bool) {
+		at = next
+		if at.Before(now) {
+			at = now
+		}
+		if lb.capacity > 0 && at.Sub(now) >= lb.interval*time.Duration(lb.capacity) {
+			full = true
+			return next, true
+		}
+		return at.Add(lb.interval), true
+	})
+	if full {
+		return ErrBucketFull
+	}
+
+	wait := at.Sub(now)
+	if wait <= 0 {
+		return nil
+	}
+	timer := time.NewTimer(wait)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Pending 返回key当前排队等待的调用数
+func (lb *LeakyBucket[K]) Pending(key K) int {
+	next, ok := lb.shards.ShardFor(key).Get(key)
+	if !ok {
+		return 0
+	}
+	return Max(int(time.Until(next)/lb.interval), 0)
+}
+
+// Sweep 清理已无排队的key，可由使用方定期调用
+func (lb *LeakyBucket[K]) Sweep() {
+	now := time.Now()
+	for _, shard := range lb.shards {
+		var idle []K
+		shard.Range(func(k K, next time.Time) bool {
+			if !next.After(now) {
+				idle = append(idle, k)
+			}
+			return true
+		})
+		for _, k := range idle {
+			shard.Compute(k, func(next time.Time, _ bool) (time.Time, bool) {
+				return next, next.After(now)
+			})
+		}
+	}
+}
@@ -0,0 +1,75 @@
+package common
+
+import (
+	"context"
+	"slices"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestLeakyBucket(t *testing.T) {
+	const interval = 20 * time.Millisecond
+	lb := NewLeakyBucket[string](4, interval, 3)
+
+	// 首个调用立即放行，之后最多3个排队，依次间隔interval放行
+	var (
+		mu       sync.Mutex
+		released []time.Time
+		wg       sync.WaitGroup
+	)
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if err := lb.Submit(context.Background(), "a"); err != nil {
+				t.Error(err)
+				return
+			}
+			mu.Lock()
+			released = append(released, time.Now())
+			mu.Unlock()
+		}()
+	}
+	deadline := time.Now().Add(time.Second)
+	for lb.Pending("a") < 3 && time.Now().Before(deadline) {
+		time.Sleep(time.Millisecond)
+	}
+
+	// 已满时立即拒绝，其他key不受影响
+	if err := lb.Submit(context.Background(), "a"); err != ErrBucketFull {
+		t.Fatalf("expected ErrBucketFull, got %v", err)
+	}
+	if err := lb.Submit(context.Background(), "b"); err != nil {
+		t.Fatal(err)
+	}
+
+	wg.Wait()
+	if len(released) != 4 {
+		t.Fatalf("released %d", len(released))
+	}
+	slices.SortFunc(released, func(a, b time.Time) int { return a.Compare(b) })
+	for i := 1; i < len(released); i++ {
+		if gap := released[i].Sub(released[i-1]); gap < interval*3/4 {
+			t.Fatalf("submissions %d and %d released %v apart", i-1, i, gap)
+		}
+	}
+
+	// ctx先结束时返回ctx.Err
+	lb.Submit(context.Background(), "c")
+	ctx, cancel := context.WithTimeout(context.Background(), time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	if err := lb.Submit(ctx, "c"); err != context.DeadlineExceeded {
+		t.Fatalf("expected deadline exceeded, got %v", err)
+	}
+	if waited := time.Since(start); waited >= interval {
+		t.Fatalf("waited %v after ctx ended", waited)
+	}
+
+	time.Sleep(interval * 4)
+	lb.Sweep()
+	if lb.Pending("a") != 0 || lb.Pending("c") != 0 {
+		t.Fatal("keys still pending after sweep")
+	}
+}
@@ -2,38 +2,109 @@ package common
 
 import (
 	"sync"
+	"sync/atomic"
 
 	"github.com/samber/mo"
 )
 
 type SyncMap[K comparable, T any] struct {
-	mu *sync.RWMutex
-	d  map[K]T
+	mu    *sync.RWMutex
+	d     map[K]T
+	watch *mapWatchers[K, T]
+
+	contended atomic.Uint64 // 只在竞争时计数，无竞争的加锁路径不写共享计数
+}
+
+// lock TryLock失败即视为一次锁竞争
+func (lm *SyncMap[K, T]) lock() {
+	if !lm.mu.TryLock() {
+		lm.contended.Add(1)
+		lm.mu.Lock()
+	}
+}
+
+func (lm *SyncMap[K, T]) unlock() {
+	lm.mu.Unlock()
+}
+
+func (lm *SyncMap[K, T]) rlock() {
+	if !lm.mu.TryRLock() {
+		lm.contended.Add(1)
+		lm.mu.RLock()
+	}
+}
+
+func (lm *SyncMap[K, T]) runlock() {
+	lm.mu.RUnlock()
 }
 
 func (lm *SyncMap[K, T]) Get(k K) (T, bool) {
-	lm.mu.RLock()
-	defer lm.mu.RUnlock()
+	lm.rlock()
+	defer lm.runlock()
 	v, ok := lm.d[k]
 	return v, ok
 }
 
 func (lm *SyncMap[K, T]) Update(key K, n T) {
-	lm.mu.Lock()
-	defer lm.mu.Unlock()
+	lm.lock()
+	defer lm.unlock()
 	lm.d[key] = n
+	lm.notify(key, n)
 }
 
 func (lm *SyncMap[K, T]) UpdateIf(key K, n T, f func(T, T) bool) (update bool) {
-	lm.mu.Lock()
-	defer lm.mu.Unlock()
+	lm.lock()
+	defer lm.unlock()
 	old, ok := lm.d[key]
 	if update = !ok || f(old, n); update {
 		lm.d[key] = n
+		lm.notify(key, n)
 	}
 	return
 }
 
+// Compute 在写锁内根据旧值计算新值，keep为false时删除该key
+func (lm *SyncMap[K, T]) Compute(key K, f func(old T, loaded bool) (n T, keep bool)) (T, bool) {
+	lm.lock()
+	defer lm.unlock()
+	old, ok := lm.d[key]
+	n, keep := f(old, ok)
+	if keep {
+		lm.d[key] = n
+		lm.notify(key, n)
+	} else if ok {
+		delete(lm.d, key)
+		lm.notifyDelete(key)
+	}
+	return n, keep
+}
+
+func (lm *SyncMap[K, T]) Delete(key K) {
+	lm.lock()
+	defer lm.unlock()
+	if _, ok := lm.d[key]; ok {
+		delete(lm.d, key)
+		lm.notifyDelete(key)
+	}
+}
+
+func (lm *SyncMap[K, T]) Len() int {
+	lm.rlock()
+	defer lm.runlock()
+	return len(lm.d)
+}
+
+// Range 遍历期间持有读锁，f中不能修改该map
+func (lm *SyncMap[K, T]) Range(f func(K, T) bool) {
+	lm.rlock()
+	defer lm.runlock()
+	for k, v := range lm.d {
+		if !f(k, v) {
+			return
+		}
+	}
+}
+
 func NewSyncMap[K comparable, T any](capacity int) *SyncMap[K, T] {
 	return &SyncMap[K, T]{
 		mu: &sync.RWMutex{},
@@ -41,12 +112,15 @@ func NewSyncMap[K comparable, T any](capacity int) *SyncMap[K, T] {
 	}
 }
 
-func NewSyncMapGroup[K comparable, T any](g, c int) []*SyncMap[K, T] {
+// SyncMapGroup 分片的SyncMap，分片数为2的幂
+type SyncMapGroup[K comparable, T any] []*SyncMap[K, T]
+
+func NewSyncMapGroup[K comparable, T any](g, c int) SyncMapGroup[K, T] {
 	if !IsPowerOfTwo(g) {
 		panic("not power of two")
 	}
 
-	r := make([]*SyncMap[K, T], g)
+	r := make(SyncMapGroup[K, T], g)
 	for i := 0; i < g; i++ {
 		r[i] = NewSyncMap[K, T](c)
 	}
@@ -54,6 +128,30 @@ func NewSyncMapGroup[K comparable, T any](g, c int) []*SyncMap[K, T] {
 	return r
 }
 
+// ShardFor 返回key所属的分片
+func (g SyncMapGroup[K, T]) ShardFor(key K) *SyncMap[K, T] {
+	return g[HashKey(key)&uint64(len(g)-1)]
+}
+
+type ShardStat struct {
+	Shard     int
+	Len       int
+	Contended uint64 // 加锁时发生等待的次数
+}
+
+// ShardStats 返回各分片的元素数量和锁竞争估计，用于发现热点分片
+func (g SyncMapGroup[K, T]) ShardStats() []ShardStat {
+	r := make([]ShardStat, len(g))
+	for i, shard := range g {
+		r[i] = ShardStat{
+			Shard:     i,
+			Len:       shard.Len(),
+			Contended: shard.contended.Load(),
+		}
+	}
+	return r
+}
+
 func ClearMap[M ~map[K]V, K comparable, V any](data M) {
 	if len(data) == 0 {
 		return
@@ -0,0 +1,116 @@
+package common
+
+import (
+	"sync"
+)
+
+const (
+	defaultMapShards = 32
+)
+
+// Map SyncMap各种实现的公共接口，调用方可按读写比例切换实现
+type Map[K comparable, T any] interface {
+	Get(K) (T, bool)
+	Update(K, T)
+	Delete(K)
+	Len() int
+	Range(func(K, T) bool)
+}
+
+type MapBackend int
+
+const (
+	MapBackendRWMutex MapBackend = iota // SyncMap，读写均衡
+	MapBackendSyncMap                   // sync.Map，key稳定、读远多于写
+	MapBackendSharded                   // SyncMapGroup，高并发写
+)
+
+func NewMap[K comparable, T any](backend MapBackend, capacity int) Map[K, T] {
+	switch backend {
+	case MapBackendSyncMap:
+		return NewStdSyncMap[K, T]()
+	case MapBackendSharded:
+		return NewSyncMapGroup[K, T](defaultMapShards, capacity/defaultMapShards+1)
+	default:
+		return NewSyncMap[K, T](capacity)
+	}
+}
+
+var (
+	_ Map[int, int] = (*SyncMap[int, int])(nil)
+	_ Map[int, int] = (*StdSyncMap[int, int])(nil)
+	_ Map[int, int] = SyncMapGroup[int, int](nil)
+)
+
+func (g SyncMapGroup[K, T]) Get(k K) (T, bool) {
+	return g.ShardFor(k).Get(k)
+}
+
+func (g SyncMapGroup[K, T]) Update(k K, n T) {
+	g.ShardFor(k).Update(k, n)
+}
+
+func (g SyncMapGroup[K, T]) Delete(k K) {
+	g.ShardFor(k).Delete(k)
+}
+
+func (g SyncMapGroup[K, T]) Len() (n int) {
+	for _, shard := range g {
+		n += shard.Len()
+	}
+	return
+}
+
+// Range 依次遍历各分片，每次只持有一个分片的读锁
+func (g SyncMapGroup[K, T]) Range(f func(K, T) bool) {
+	for _, shard := range g {
+		stop := false
+		shard.Range(func(k K, v T) bool {
+			stop = !f(k, v)
+			return !stop
+		})
+		if stop {
+			return
+		}
+	}
+}
+
+// StdSyncMap sync.Map的泛型封装
+type StdSyncMap[K comparable, T any] struct {
+	m sync.Map
+}
+
+func NewStdSyncMap[K comparable, T any]() *StdSyncMap[K, T] {
+	return &StdSyncMap[K, T]{}
+}
+
+func (sm *StdSyncMap[K, T]) Get(k K) (v T, ok bool) {
+	var r any
+	if r, ok = sm.m.Load(k); ok {
+		v = r.(T)
+	}
+	return
+}
+
+func (sm *StdSyncMap[K, T]) Update(k K, n T) {
+	sm.m.Store(k, n)
+}
+
+func (sm *StdSyncMap[K, T]) Delete(k K) {
+	sm.m.Delete(k)
+}
+
+// Len 需要遍历，O(n)
+func (sm *StdSyncMap[K, T]) Len() (n int) {
+	sm.m.Range(func(_, _ any) bool {
+		n++
+		return true
+	})
+	return
+}
+
+func (sm *StdSyncMap[K, T]) Range(f func(K, T) bool) {
+	sm.m.Range(func(k, v any) bool {
+		return f(k.(K), v.(T))
+	})
+}
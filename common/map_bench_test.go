@@ -0,0 +1,47 @@
+package common
+
+import (
+	"strconv"
+	"testing"
+)
+
+const benchKeys = 1 << 12
+
+var benchBackends = map[string]MapBackend{
+	"rwmutex": MapBackendRWMutex,
+	"syncmap": MapBackendSyncMap,
+	"sharded": MapBackendSharded,
+}
+
+func benchmarkMap(b *testing.B, writeEvery int) {
+	keys := make([]string, benchKeys)
+	for i := range keys {
+		keys[i] = strconv.Itoa(i)
+	}
+
+	for name, backend := range benchBackends {
+		b.Run(name, func(b *testing.B) {
+			m := NewMap[string, int](backend, benchKeys)
+			for i, k := range keys {
+				m.Update(k, i)
+			}
+			b.ResetTimer()
+			b.RunParallel(func(pb *testing.PB) {
+				i := 0
+				for pb.Next() {
+					k := keys[i&(benchKeys-1)]
+					if i%writeEvery == 0 {
+						m.Update(k, i)
+					} else {
+						m.Get(k)
+					}
+					i++
+				}
+			})
+		})
+	}
+}
+
+func BenchmarkMapReadMostly(b *testing.B) { benchmarkMap(b, 100) }
+func BenchmarkMapMixed(b *testing.B)      { benchmarkMap(b, 10) }
+func BenchmarkMapWriteHeavy(b *testing.B) { benchmarkMap(b, 2) }
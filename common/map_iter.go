@@ -0,0 +1,53 @@
+package common
+
+import (
+	"iter"
+)
+
+// snapshot 在读锁内复制分片数据
+func (lm *SyncMap[K, T]) snapshot() map[K]T {
+	lm.rlock()
+	defer lm.runlock()
+	return CloneMap(lm.d)
+}
+
+// All 逐个分片复制后再遍历，任一时刻最多持有一个分片的锁，遍历过程中可以修改该map；
+// 结果为弱一致快照，不同分片的数据可能来自不同时刻
+func (g SyncMapGroup[K, T]) All() iter.Seq2[K, T] {
+	return func(yield func(K, T) bool) {
+		for _, shard := range g {
+			for k, v := range shard.snapshot() {
+				if !yield(k, v) {
+					return
+				}
+			}
+		}
+	}
+}
+
+// Snapshot 返回所有分片数据的弱一致副本
+func (g SyncMapGroup[K, T]) Snapshot() map[K]T {
+	r := make(map[K]T, g.Len())
+	for k, v := range g.All() {
+		r[k] = v
+	}
+	return r
+}
+
+// ParallelRange 最多concurrency个分片并发处理，每个分片先复制再调用f，不阻塞写入；
+// 某个分片内f返回错误时跳过该分片剩余元素，所有错误聚合后返回
+func (g SyncMapGroup[K, T]) ParallelRange(concurrency int, f func(K, T) error) error {
+	tg := NewWeightedTaskGroup(concurrency)
+	for _, shard := range g {
+		shard := shard
+		tg.Go(func() error {
+			for k, v := range shard.snapshot() {
+				if err := f(k, v); err != nil {
+					return err
+				}
+			}
+			return nil
+		})
+	}
+	return tg.Wait()
+}
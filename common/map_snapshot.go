@@ -0,0 +1,134 @@
+package common
+
+import (
+	"encoding/gob"
+	"encoding/json"
+	"errors"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// Codec 快照编解码器
+type Codec interface {
+	Encode(w io.Writer, v any) error
+	Decode(r io.Reader, v any) error
+}
+
+type gobCodec struct{}
+
+func (gobCodec) Encode(w io.Writer, v any) error { return gob.NewEncoder(w).Encode(v) }
+func (gobCodec) Decode(r io.Reader, v any) error { return gob.NewDecoder(r).Decode(v) }
+
+type jsonCodec struct{}
+
+func (jsonCodec) Encode(w io.Writer, v any) error { return json.NewEncoder(w).Encode(v) }
+func (jsonCodec) Decode(r io.Reader, v any) error { return json.NewDecoder(r).Decode(v) }
+
+var (
+	GobCodec  Codec = gobCodec{}
+	JSONCodec Codec = jsonCodec{}
+)
+
+// SaveTo 将当前数据快照写入w，codec为nil时使用GobCodec
+func (lm *SyncMap[K, T]) SaveTo(w io.Writer, codec Codec) error {
+	if codec == nil {
+		codec = GobCodec
+	}
+	lm.rlock()
+	snapshot := CloneMap(lm.d)
+	lm.runlock()
+	return codec.Encode(w, snapshot)
+}
+
+// LoadFrom 从r读取快照并替换当前数据，codec为nil时使用GobCodec
+func (lm *SyncMap[K, T]) LoadFrom(r io.Reader, codec Codec) error {
+	if codec == nil {
+		codec = GobCodec
+	}
+	d := make(map[K]T)
+	if err := codec.Decode(r, &d); err != nil {
+		return err
+	}
+	lm.lock()
+	defer lm.unlock()
+	lm.d = d
+	for k, v := range d {
+		lm.notify(k, v)
+	}
+	return nil
+}
+
+// SaveToFile 先写临时文件再rename，避免进程中途退出留下半个快照
+func (lm *SyncMap[K, T]) SaveToFile(path string, codec Codec) error {
+	return writeFileAtomic(path, func(w io.Writer) error {
+		return lm.SaveTo(w, codec)
+	})
+}
+
+func writeFileAtomic(path string, write func(io.Writer) error) error {
+	tmp, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".tmp*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	if err := write(tmp); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
+
+// LoadFromFile 从快照文件恢复，文件不存在时忽略
+func (lm *SyncMap[K, T]) LoadFromFile(path string, codec Codec) error {
+	f, err := os.Open(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	} else if err != nil {
+		return err
+	}
+	defer f.Close()
+	return lm.LoadFrom(f, codec)
+}
+
+// AutoSnapshot 每隔interval将快照写入path，返回的stop会停止定时任务并写入最后一次快照
+func (lm *SyncMap[K, T]) AutoSnapshot(path string, interval time.Duration, codec Codec) (stop func() error) {
+	var (
+		wg     sync.WaitGroup
+		stopCh = make(chan struct{})
+		once   sync.Once
+	)
+
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-stopCh:
+				return
+			case <-ticker.C:
+				if err := lm.SaveToFile(path, codec); err != nil {
+					log.Printf("auto snapshot to %s failed: %v", path, err)
+				}
+			}
+		}
+	}()
+
+	return func() (err error) {
+		once.Do(func() {
+			close(stopCh)
+			wg.Wait()
+			err = lm.SaveToFile(path, codec)
+		})
+		return
+	}
+}
@@ -0,0 +1,78 @@
+package common
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestSyncMapSnapshot(t *testing.T) {
+	for name, codec := range map[string]Codec{"gob": GobCodec, "json": JSONCodec} {
+		src := NewSyncMap[string, int](4)
+		src.Update("a", 1)
+		src.Update("b", 2)
+
+		var buf bytes.Buffer
+		if err := src.SaveTo(&buf, codec); err != nil {
+			t.Fatalf("%s: save: %v", name, err)
+		}
+
+		dst := NewSyncMap[string, int](4)
+		dst.Update("stale", 3)
+		if err := dst.LoadFrom(&buf, codec); err != nil {
+			t.Fatalf("%s: load: %v", name, err)
+		}
+		if v, ok := dst.Get("b"); !ok || v != 2 {
+			t.Fatalf("%s: got %v %v, want 2 true", name, v, ok)
+		}
+		if _, ok := dst.Get("stale"); ok {
+			t.Fatalf("%s: stale key survived restore", name)
+		}
+	}
+}
+
+func TestSyncMapWatchAllDelete(t *testing.T) {
+	m := NewSyncMap[string, int](4)
+	ch := m.WatchAll()
+	m.Update("a", 1)
+	m.Update("b", 2)
+	m.Delete("a")
+	m.Delete("missing")
+	m.Compute("b", func(int, bool) (int, bool) { return 0, false })
+
+	want := []MapEvent[string, int]{
+		{Key: "a", Value: 1},
+		{Key: "b", Value: 2},
+		{Key: "a", Deleted: true},
+		{Key: "b", Deleted: true},
+	}
+	for i, w := range want {
+		if got := <-ch; got != w {
+			t.Fatalf("event %d: got %+v, want %+v", i, got, w)
+		}
+	}
+	select {
+	case e := <-ch:
+		t.Fatalf("unexpected event %+v", e)
+	default:
+	}
+}
+
+func TestOrderedMap(t *testing.T) {
+	om := NewOrderedMap[string, int](4)
+	om.Set("c", 1)
+	om.Set("a", 2)
+	om.Set("b", 3)
+	om.Set("c", 4) // update keeps position
+	om.Delete("a")
+
+	keys := om.Keys()
+	if len(keys) != 2 || keys[0] != "c" || keys[1] != "b" {
+		t.Fatalf("unexpected order %v", keys)
+	}
+	if k, v, ok := om.PopOldest(); !ok || k != "c" || v != 4 {
+		t.Fatalf("PopOldest got %v %v %v", k, v, ok)
+	}
+	if k, _, ok := om.Newest(); !ok || k != "b" {
+		t.Fatalf("Newest got %v %v", k, ok)
+	}
+}
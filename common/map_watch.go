@@ -0,0 +1,121 @@
+package common
+
+const (
+	watchAllBuffer = 64
+)
+
+type MapEvent[K comparable, T any] struct {
+	Key     K
+	Value   T
+	Deleted bool // key被删除，此时Value为零值
+}
+
+type mapWatchers[K comparable, T any] struct {
+	keys map[K][]chan T
+	all  []chan MapEvent[K, T]
+}
+
+// Watch 订阅key的更新，channel只保留最新值，慢消费者会丢失中间值；
+// 删除不会通知，需要感知删除时使用WatchAll
+func (lm *SyncMap[K, T]) Watch(k K) <-chan T {
+	ch := make(chan T, 1)
+	lm.lock()
+	defer lm.unlock()
+	w := lm.watchers()
+	w.keys[k] = append(w.keys[k], ch)
+	return ch
+}
+
+// WatchAll 订阅所有key的更新和删除，缓冲区满时丢弃最旧的事件
+func (lm *SyncMap[K, T]) WatchAll() <-chan MapEvent[K, T] {
+	ch := make(chan MapEvent[K, T], watchAllBuffer)
+	lm.lock()
+	defer lm.unlock()
+	w := lm.watchers()
+	w.all = append(w.all, ch)
+	return ch
+}
+
+// Unwatch 取消Watch订阅并关闭channel
+func (lm *SyncMap[K, T]) Unwatch(k K, ch <-chan T) {
+	lm.lock()
+	defer lm.unlock()
+	if lm.watch == nil {
+		return
+	}
+	chs := lm.watch.keys[k]
+	for i, c := range chs {
+		if c == ch {
+			close(c)
+			chs = append(chs[:i], chs[i+1:]...)
+			break
+		}
+	}
+	if len(chs) == 0 {
+		delete(lm.watch.keys, k)
+	} else {
+		lm.watch.keys[k] = chs
+	}
+}
+
+// UnwatchAll 取消WatchAll订阅并关闭channel
+func (lm *SyncMap[K, T]) UnwatchAll(ch <-chan MapEvent[K, T]) {
+	lm.lock()
+	defer lm.unlock()
+	if lm.watch == nil {
+		return
+	}
+	for i, c := range lm.watch.all {
+		if c == ch {
+			close(c)
+			lm.watch.all = append(lm.watch.all[:i], lm.watch.all[i+1:]...)
+			return
+		}
+	}
+}
+
+func (lm *SyncMap[K, T]) watchers() *mapWatchers[K, T] {
+	if lm.watch == nil {
+		lm.watch = &mapWatchers[K, T]{
+			keys: make(map[K][]chan T),
+		}
+	}
+	return lm.watch
+}
+
+// notify 需在持有写锁时调用
+func (lm *SyncMap[K, T]) notify(k K, v T) {
+	if lm.watch == nil {
+		return
+	}
+	for _, ch := range lm.watch.keys[k] {
+		sendLatest(ch, v)
+	}
+	for _, ch := range lm.watch.all {
+		sendLatest(ch, MapEvent[K, T]{Key: k, Value: v})
+	}
+}
+
+// notifyDelete 需在持有写锁时调用
+func (lm *SyncMap[K, T]) notifyDelete(k K) {
+	if lm.watch == nil {
+		return
+	}
+	for _, ch := range lm.watch.all {
+		sendLatest(ch, MapEvent[K, T]{Key: k, Deleted: true})
+	}
+}
+
+func sendLatest[T any](ch chan T, v T) {
+	for {
+		select {
+		case ch <- v:
+			return
+		default:
+		}
+		select {
+		case <-ch:
+		default:
+		}
+	}
+}
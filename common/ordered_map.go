@@ -0,0 +1,163 @@
+package common
+
+import (
+	"container/list"
+	"sync"
+)
+
+type orderedEntry[K comparable, V any] struct {
+	key   K
+	value V
+}
+
+// OrderedMap 按插入顺序迭代的map，非并发安全
+type OrderedMap[K comparable, V any] struct {
+	l *list.List
+	m map[K]*list.Element
+}
+
+func NewOrderedMap[K comparable, V any](capacity int) *OrderedMap[K, V] {
+	return &OrderedMap[K, V]{
+		l: list.New(),
+		m: make(map[K]*list.Element, capacity),
+	}
+}
+
+func (om *OrderedMap[K, V]) Get(k K) (v V, ok bool) {
+	if e, ok := om.m[k]; ok {
+		return e.Value.(*orderedEntry[K, V]).value, true
+	}
+	return
+}
+
+// Set 已存在的key只更新值，不改变其顺序
+func (om *OrderedMap[K, V]) Set(k K, v V) {
+	if e, ok := om.m[k]; ok {
+		e.Value.(*orderedEntry[K, V]).value = v
+		return
+	}
+	om.m[k] = om.l.PushBack(&orderedEntry[K, V]{key: k, value: v})
+}
+
+func (om *OrderedMap[K, V]) Delete(k K) (ok bool) {
+	var e *list.Element
+	if e, ok = om.m[k]; ok {
+		om.l.Remove(e)
+		delete(om.m, k)
+	}
+	return
+}
+
+func (om *OrderedMap[K, V]) Len() int {
+	return len(om.m)
+}
+
+func (om *OrderedMap[K, V]) Oldest() (k K, v V, ok bool) {
+	return om.entry(om.l.Front())
+}
+
+func (om *OrderedMap[K, V]) Newest() (k K, v V, ok bool) {
+	return om.entry(om.l.Back())
+}
+
+// PopOldest 删除并返回最早插入的元素，用于FIFO淘汰
+func (om *OrderedMap[K, V]) PopOldest() (k K, v V, ok bool) {
+	if k, v, ok = om.Oldest(); ok {
+		om.Delete(k)
+	}
+	return
+}
+
+// Range 按插入顺序遍历，f返回false时停止
+func (om *OrderedMap[K, V]) Range(f func(K, V) bool) {
+	for e := om.l.Front(); e != nil; e = e.Next() {
+		entry := e.Value.(*orderedEntry[K, V])
+		if !f(entry.key, entry.value) {
+			return
+		}
+	}
+}
+
+func (om *OrderedMap[K, V]) Keys() []K {
+	keys := make([]K, 0, len(om.m))
+	for e := om.l.Front(); e != nil; e = e.Next() {
+		keys = append(keys, e.Value.(*orderedEntry[K, V]).key)
+	}
+	return keys
+}
+
+func (om *OrderedMap[K, V]) entry(e *list.Element) (k K, v V, ok bool) {
+	if e == nil {
+		return
+	}
+	entry := e.Value.(*orderedEntry[K, V])
+	return entry.key, entry.value, true
+}
+
+// SyncOrderedMap 并发安全的OrderedMap
+type SyncOrderedMap[K comparable, V any] struct {
+	mu *sync.RWMutex
+	om *OrderedMap[K, V]
+}
+
+func NewSyncOrderedMap[K comparable, V any](capacity int) *SyncOrderedMap[K, V] {
+	return &SyncOrderedMap[K, V]{
+		mu: &sync.RWMutex{},
+		om: NewOrderedMap[K, V](capacity),
+	}
+}
+
+func (sm *SyncOrderedMap[K, V]) Get(k K) (V, bool) {
+	sm.mu.RLock()
+	defer sm.mu.RUnlock()
+	return sm.om.Get(k)
+}
+
+func (sm *SyncOrderedMap[K, V]) Set(k K, v V) {
+	sm.mu.Lock()
+	defer sm.mu.Unlock()
+	sm.om.Set(k, v)
+}
+
+func (sm *SyncOrderedMap[K, V]) Delete(k K) bool {
+	sm.mu.Lock()
+	defer sm.mu.Unlock()
+	return sm.om.Delete(k)
+}
+
+func (sm *SyncOrderedMap[K, V]) Len() int {
+	sm.mu.RLock()
+	defer sm.mu.RUnlock()
+	return sm.om.Len()
+}
+
+func (sm *SyncOrderedMap[K, V]) Oldest() (K, V, bool) {
+	sm.mu.RLock()
+	defer sm.mu.RUnlock()
+	return sm.om.Oldest()
+}
+
+func (sm *SyncOrderedMap[K, V]) Newest() (K, V, bool) {
+	sm.mu.RLock()
+	defer sm.mu.RUnlock()
+	return sm.om.Newest()
+}
+
+func (sm *SyncOrderedMap[K, V]) PopOldest() (K, V, bool) {
+	sm.mu.Lock()
+	defer sm.mu.Unlock()
+	return sm.om.PopOldest()
+}
+
+// Range 遍历期间持有读锁，f中不能修改该map
+func (sm *SyncOrderedMap[K, V]) Range(f func(K, V) bool) {
+	sm.mu.RLock()
+	defer sm.mu.RUnlock()
+	sm.om.Range(f)
+}
+
+func (sm *SyncOrderedMap[K, V]) Keys() []K {
+	sm.mu.RLock()
+	defer sm.mu.RUnlock()
+	return sm.om.Keys()
+}
@@ -1,62 +1,200 @@
 package common
 
 import (
-	"math/rand"
+	"sync"
 	"time"
 
 	"go.uber.org/atomic"
 )
 
+// Pacer 每个pace窗口最多执行一次f，多个goroutine并发调用时通过CAS保证只有一个执行。
+// NewPacerWithBurst创建的Pacer在任意pace时长内最多执行burst次
 type Pacer struct {
-	last *atomic.Time
-	pace time.Duration
+	last *atomic.Int64 // 上次执行的UnixNano
+	pace *atomic.Duration
+
+	jitter *atomic.Float64 // 每个窗口随机延长的比例
+	rand   Rand
+	window *atomic.Duration // 当前窗口的长度，启用jitter时每次执行后重新抽取
+
+	executed *atomic.Uint64
+	skipped  *atomic.Uint64
+
+	mu     sync.Mutex
+	recent *timeRing // burst>1时记录最近的执行时间
 }
 
 func (p *Pacer) Go(f func()) {
-	if now := time.Now(); now.Sub(p.last.Load()) > p.pace {
-		p.last.Store(now)
+	if p.allow() {
 		go f()
 	}
 }
 
 func (p *Pacer) Run(f func()) {
-	if now := time.Now(); now.Sub(p.last.Load()) > p.pace {
-		p.last.Store(now)
+	if p.allow() {
 		f()
 	}
 }
 
+// RunErr 同Run，ran表示本次是否执行，用于区分"被节流跳过"与"执行但失败"
+func (p *Pacer) RunErr(f func() error) (ran bool, err error) {
+	if !p.allow() {
+		return false, nil
+	}
+	return true, f()
+}
+
+// Stats 返回执行和因节流跳过的次数
+func (p *Pacer) Stats() (executed, skipped uint64) {
+	return p.executed.Load(), p.skipped.Load()
+}
+
+func (p *Pacer) allow() bool {
+	if p.claim() {
+		p.executed.Inc()
+		return true
+	}
+	p.skipped.Inc()
+	return false
+}
+
+// claim 距上次执行超过pace时抢占本窗口，CAS失败说明已被其他goroutine抢占
+func (p *Pacer) claim() bool {
+	if p.recent != nil {
+		return p.claimBurst()
+	}
+	now := time.Now().UnixNano()
+	last := p.last.Load()
+	if now-last <= int64(p.windowLen()) {
+		return false
+	}
+	if !p.last.CompareAndSwap(last, now) {
+		return false
+	}
+	p.nextWindow()
+	return true
+}
+
+// windowLen 当前窗口长度，未启用jitter时为pace
+func (p *Pacer) windowLen() time.Duration {
+	if w := p.window.Load(); w > 0 {
+		return w
+	}
+	return p.pace.Load()
+}
+
+func (p *Pacer) nextWindow() {
+	if frac := p.jitter.Load(); frac > 0 {
+		p.window.Store(Jitter(p.rand, p.pace.Load(), frac))
+	}
+}
+
+// WithJitter 每次执行后将下一个窗口随机延长[0, pace*frac)，避免多个实例对同一下游的节奏逐渐同步；
+// r为nil时使用DefaultRand
+func (p *Pacer) WithJitter(frac float64, r Rand) *Pacer {
+	if r != nil {
+		p.rand = r
+	}
+	p.jitter.Store(frac)
+	if frac <= 0 {
+		p.window.Store(0)
+	}
+	return p
+}
+
+func (p *Pacer) claimBurst() bool {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	now := time.Now()
+	p.recent.evict(now, p.windowLen())
+	if p.recent.n >= len(p.recent.times) {
+		return false
+	}
+	p.recent.push(now)
+	p.last.Store(now.UnixNano())
+	p.nextWindow()
+	return true
+}
+
 func NewPacer(pace time.Duration) *Pacer {
 	return &Pacer{
-		pace: pace,
-		last: atomic.NewTime(time.Now().Add(-pace * 2)),
+		pace: atomic.NewDuration(pace),
+		last: atomic.NewInt64(time.Now().Add(-pace * 2).UnixNano()),
+
+		jitter: atomic.NewFloat64(0),
+		rand:   DefaultRand,
+		window: atomic.NewDuration(0),
+
+		executed: atomic.NewUint64(0),
+		skipped:  atomic.NewUint64(0),
 	}
 }
 
-func NewPacerWithRand(pace time.Duration, extraSec int) *Pacer {
-	randpace := time.Duration(rand.Intn(extraSec)) * time.Second
-	return &Pacer{
-		pace: pace + randpace,
+// SetPace 运行时调整间隔，从下一次调用开始生效
+func (p *Pacer) SetPace(pace time.Duration) {
+	p.pace.Store(pace)
+	p.nextWindow()
+}
+
+func (p *Pacer) Pace() time.Duration {
+	return p.pace.Load()
+}
+
+// NewPacerWithBurst 任意pace时长内最多执行burst次，允许偶发的小突发同时限制持续速率
+func NewPacerWithBurst(pace time.Duration, burst int) *Pacer {
+	p := NewPacer(pace)
+	if burst > 1 {
+		p.recent = newTimeRing(burst)
 	}
+	return p
+}
+
+func NewPacerWithRand(pace time.Duration, extraSec int) *Pacer {
+	return NewPacerWithRandSource(pace, extraSec, DefaultRand)
+}
+
+// NewPacerWithRandSource 同NewPacerWithRand，使用指定的随机源
+func NewPacerWithRandSource(pace time.Duration, extraSec int, r Rand) *Pacer {
+	return NewPacer(pace + RandDuration(r, time.Duration(extraSec)*time.Second).Truncate(time.Second))
 }
 
+// TickPacer 按调用次数节流，每pace次调用最多执行一次，可并发调用
 type TickPacer struct {
-	pace uint64
-	tick uint64
-	last uint64
+	pace *atomic.Uint64
+	tick *atomic.Uint64
+	last *atomic.Uint64
 }
 
 func (p *TickPacer) Go(f func()) {
-	p.tick++
-	if (p.tick - p.last) > p.pace {
-		p.last = p.tick
-
-		go f()
+	tick := p.tick.Inc()
+	for {
+		last := p.last.Load()
+		// 持有更大tick的调用已执行时last>tick，直接相减会回绕
+		if tick <= last || tick-last <= p.pace.Load() {
+			return
+		}
+		if p.last.CompareAndSwap(last, tick) {
+			go f()
+			return
+		}
 	}
 }
 
+// Reset 清空计数，下一次执行需重新累计pace次调用
+func (p *TickPacer) Reset() {
+	p.tick.Store(0)
+	p.last.Store(0)
+}
+
+// SetPace 运行时调整间隔次数
+func (p *TickPacer) SetPace(pace uint64) {
+	p.pace.Store(pace)
+}
+
 func NewTickPacer(pace uint64) *TickPacer {
 	return &TickPacer{
-		pace: pace,
+		pace: atomic.NewUint64(pace),
+		tick: atomic.NewUint64(0),
+		last: atomic.NewUint64(0),
 	}
 }
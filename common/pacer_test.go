@@ -0,0 +1,73 @@
+package common
+
+import (
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestPacerConcurrentRun(t *testing.T) {
+	p := NewPacer(time.Hour)
+	var (
+		runs atomic.Int64
+		wg   sync.WaitGroup
+	)
+	for i := 0; i < 64; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			p.Run(func() { runs.Add(1) })
+		}()
+	}
+	wg.Wait()
+	if runs.Load() != 1 {
+		t.Fatalf("ran %d times in one window", runs.Load())
+	}
+}
+
+func TestPacerBurst(t *testing.T) {
+	p := NewPacerWithBurst(time.Hour, 3)
+	runs := 0
+	for i := 0; i < 10; i++ {
+		p.Run(func() { runs++ })
+	}
+	if runs != 3 {
+		t.Fatalf("ran %d times, want 3", runs)
+	}
+	if executed, skipped := p.Stats(); executed != 3 || skipped != 7 {
+		t.Fatalf("stats %d/%d", executed, skipped)
+	}
+}
+
+func TestTickPacerConcurrent(t *testing.T) {
+	const (
+		pace    = 3
+		workers = 16
+		calls   = 1000
+	)
+	p := NewTickPacer(pace)
+	var (
+		runs atomic.Int64
+		wg   sync.WaitGroup
+	)
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < calls; j++ {
+				p.Go(func() { runs.Add(1) })
+			}
+		}()
+	}
+	wg.Wait()
+	time.Sleep(50 * time.Millisecond)
+
+	// 每次执行需比上次多pace次以上调用，last只能前进
+	if n := runs.Load(); n == 0 || n > workers*calls/(pace+1) {
+		t.Fatalf("ran %d times for %d calls with pace %d", n, workers*calls, pace)
+	}
+	if last, tick := p.last.Load(), p.tick.Load(); last > tick || tick-last > workers*calls {
+		t.Fatalf("last %d moved past tick %d", last, tick)
+	}
+}
@@ -0,0 +1,25 @@
+package common
+
+// ParallelForEach 最多limit个并发对items逐个执行fn，等待全部结束后返回聚合错误
+func ParallelForEach[T any](items []T, limit int, fn func(T) error) error {
+	wg := NewWeightedTaskGroup(limit)
+	for _, item := range items {
+		wg.Go(func() error {
+			return fn(item)
+		})
+	}
+	return wg.Wait()
+}
+
+// ParallelMap 同ParallelForEach，结果与items顺序一一对应，失败项的结果为fn返回的值
+func ParallelMap[T, R any](items []T, limit int, fn func(T) (R, error)) ([]R, error) {
+	results := make([]R, len(items))
+	wg := NewWeightedTaskGroup(limit)
+	for i, item := range items {
+		wg.Go(func() (err error) {
+			results[i], err = fn(item)
+			return
+		})
+	}
+	return results, wg.Wait()
+}
@@ -0,0 +1,116 @@
+package common
+
+import (
+	"sync"
+	"sync/atomic"
+
+	"github.com/cdpzyafk/go-utils/optkit"
+)
+
+type PoolOption[T any] = optkit.Option[Pool[T]]
+
+// WithMaxIdle 最多缓存n个空闲对象，超出时Put直接丢弃；设置后空闲对象不会被GC回收
+func WithMaxIdle[T any](n int) PoolOption[T] {
+	return func(p *Pool[T]) {
+		if n > 0 {
+			p.idle = make(chan T, n)
+		}
+	}
+}
+
+// WithPoolReset Put时先调用reset清理对象状态
+func WithPoolReset[T any](reset func(T)) PoolOption[T] {
+	return func(p *Pool[T]) {
+		p.reset = reset
+	}
+}
+
+// PoolStats 对象池统计
+type PoolStats struct {
+	Gets   uint64
+	Hits   uint64 // Get从池中取到对象的次数
+	Allocs uint64 // Get时池为空、调用New新建对象的次数
+	Puts   uint64
+	Drops  uint64 // 超出WithMaxIdle被丢弃的次数
+}
+
+// HitRate 命中率，用于判断池化是否有效
+func (s PoolStats) HitRate() float64 {
+	if s.Gets == 0 {
+		return 0
+	}
+	return float64(s.Hits) / float64(s.Gets)
+}
+
+// Pool 带类型的对象池，默认基于sync.Pool，附带命中率统计
+type Pool[T any] struct {
+	pool  sync.Pool
+	idle  chan T // 设置WithMaxIdle时代替pool
+	new   func() T
+	reset func(T)
+
+	gets   atomic.Uint64
+	hits   atomic.Uint64
+	allocs atomic.Uint64
+	puts   atomic.Uint64
+	drops  atomic.Uint64
+}
+
+func NewPool[T any](new func() T, opts ...PoolOption[T]) *Pool[T] {
+	p := &Pool[T]{new: new}
+	optkit.ApplyTo(p, opts...)
+	return p
+}
+
+func (p *Pool[T]) Get() T {
+	p.gets.Add(1)
+	if p.idle != nil {
+		select {
+		case v := <-p.idle:
+			p.hits.Add(1)
+			return v
+		default:
+		}
+	} else if v := p.pool.Get(); v != nil {
+		p.hits.Add(1)
+		return v.(T)
+	}
+	p.allocs.Add(1)
+	return p.new()
+}
+
+func (p *Pool[T]) Put(v T) {
+	p.puts.Add(1)
+	if p.reset != nil {
+		p.reset(v)
+	}
+	if p.idle == nil {
+		p.pool.Put(v)
+		return
+	}
+	select {
+	case p.idle <- v:
+	default:
+		p.drops.Add(1)
+	}
+}
+
+func (p *Pool[T]) Stats() PoolStats {
+	return PoolStats{
+		Gets:   p.gets.Load(),
+		Hits:   p.hits.Load(),
+		Allocs: p.allocs.Load(),
+		Puts:   p.puts.Load(),
+		Drops:  p.drops.Load(),
+	}
+}
+
+// RegisterGauges 将统计注册到g，指标名以prefix开头
+func (p *Pool[T]) RegisterGauges(g *Gauges, prefix string) error {
+	return g.RegisterAll(prefix, []GaugeFunc{
+		{"_pool_gets", "objects requested from the pool", func() float64 { return float64(p.gets.Load()) }},
+		{"_pool_allocs", "objects allocated because the pool was empty", func() float64 { return float64(p.allocs.Load()) }},
+		{"_pool_drops", "objects dropped because the pool was full", func() float64 { return float64(p.drops.Load()) }},
+		{"_pool_hit_rate", "ratio of gets served from the pool", func() float64 { return p.Stats().HitRate() }},
+	})
+}
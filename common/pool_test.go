@@ -0,0 +1,45 @@
+package common
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestPoolMaxIdle(t *testing.T) {
+	type msg struct{ buf []byte }
+	p := NewPool(func() *msg { return &msg{buf: make([]byte, 0, 64)} },
+		WithMaxIdle[*msg](1),
+		WithPoolReset(func(m *msg) { m.buf = m.buf[:0] }),
+	)
+
+	a, b := p.Get(), p.Get()
+	a.buf = append(a.buf, "x"...)
+	p.Put(a)
+	p.Put(b) // 超出max idle
+	if c := p.Get(); c != a || len(c.buf) != 0 {
+		t.Fatal("expected the reset idle object back")
+	}
+
+	s := p.Stats()
+	if s.Gets != 3 || s.Hits != 1 || s.Allocs != 2 || s.Puts != 2 || s.Drops != 1 {
+		t.Fatalf("unexpected stats %+v", s)
+	}
+}
+
+func TestPoolRegisterGauges(t *testing.T) {
+	p := NewPool(func() []byte { return make([]byte, 8) })
+	p.Put(p.Get())
+	p.Get()
+
+	g := NewGauges("")
+	if err := p.RegisterGauges(g, "buf"); err != nil {
+		t.Fatal(err)
+	}
+	snapshot := g.Snapshot()
+	if len(snapshot) != 4 || snapshot["buf_pool_gets"] != 2 {
+		t.Fatalf("unexpected gauges %v", snapshot)
+	}
+	if err := p.RegisterGauges(g, "buf"); !errors.Is(err, ErrGaugeExists) {
+		t.Fatalf("expected ErrGaugeExists, got %v", err)
+	}
+}
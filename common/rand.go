@@ -0,0 +1,97 @@
+package common
+
+import (
+	crand "crypto/rand"
+	"encoding/binary"
+	"math/rand"
+	"sync"
+	"time"
+)
+
+// Rand 随机源抽象，测试中注入固定种子的实现即可复现抖动行为
+type Rand interface {
+	Int63n(n int64) int64
+	Float64() float64
+}
+
+// DefaultRand 未指定随机源时使用
+var DefaultRand Rand = NewSeededRand(time.Now().UnixNano())
+
+type seededRand struct {
+	mu sync.Mutex
+	r  *rand.Rand
+}
+
+// NewSeededRand 并发安全的伪随机源
+func NewSeededRand(seed int64) Rand {
+	return &seededRand{r: rand.New(rand.NewSource(seed))}
+}
+
+func (sr *seededRand) Int63n(n int64) int64 {
+	sr.mu.Lock()
+	defer sr.mu.Unlock()
+	return sr.r.Int63n(n)
+}
+
+func (sr *seededRand) Float64() float64 {
+	sr.mu.Lock()
+	defer sr.mu.Unlock()
+	return sr.r.Float64()
+}
+
+type cryptoRand struct{}
+
+// NewCryptoRand 基于crypto/rand的随机源
+func NewCryptoRand() Rand {
+	return cryptoRand{}
+}
+
+func (cryptoRand) uint64() uint64 {
+	var b [8]byte
+	if _, err := crand.Read(b[:]); err != nil {
+		panic(err)
+	}
+	return binary.LittleEndian.Uint64(b[:])
+}
+
+func (cr cryptoRand) Int63n(n int64) int64 {
+	if n <= 0 {
+		panic("invalid argument to Int63n")
+	}
+	// 拒绝采样，避免取模偏差
+	max := int64((1<<63 - 1) - (1<<63)%uint64(n))
+	for {
+		v := int64(cr.uint64() >> 1)
+		if v <= max {
+			return v % n
+		}
+	}
+}
+
+func (cr cryptoRand) Float64() float64 {
+	return float64(cr.uint64()>>11) / (1 << 53)
+}
+
+// RandDuration 返回[0, d)内的随机时长，d<=0时返回0
+func RandDuration(r Rand, d time.Duration) time.Duration {
+	if d <= 0 {
+		return 0
+	}
+	return time.Duration(r.Int63n(int64(d)))
+}
+
+// Jitter 在d的基础上随机增加[0, d*frac)
+func Jitter(r Rand, d time.Duration, frac float64) time.Duration {
+	return d + RandDuration(r, time.Duration(float64(d)*frac))
+}
+
+// Sample 以rate的概率返回true
+func Sample(r Rand, rate float64) bool {
+	if rate >= 1 {
+		return true
+	}
+	if rate <= 0 {
+		return false
+	}
+	return r.Float64() < rate
+}
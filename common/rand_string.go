@@ -0,0 +1,60 @@
+package common
+
+import (
+	crand "crypto/rand"
+	"encoding/base64"
+	"math/bits"
+	"unicode/utf8"
+)
+
+const (
+	AlphabetAlphaNum = "abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ0123456789"
+	AlphabetHex      = "0123456789abcdef"
+	AlphabetDigits   = "0123456789"
+
+	RANDTOKENBYTES = 32 // 256 bit, default
+)
+
+// RandBytes 返回n个crypto/rand生成的随机字节
+func RandBytes(n int) []byte {
+	b := make([]byte, n)
+	if _, err := crand.Read(b); err != nil {
+		panic(err)
+	}
+	return b
+}
+
+// RandString 从alphabet中等概率选取n个字符，基于crypto/rand，可用于密码、邀请码等。
+// alphabet最多256个字符，可包含非ASCII字符
+func RandString(n int, alphabet string) string {
+	chars := []rune(alphabet)
+	if len(chars) == 0 || len(chars) > 256 {
+		panic("RandString: alphabet must contain 1 to 256 characters")
+	}
+	// 取恰好覆盖len(chars)的低位，超出范围的字节丢弃重取，避免取模偏差
+	mask := byte(1<<bits.Len(uint(len(chars)-1)) - 1)
+	out := make([]byte, 0, n*utf8.UTFMax)
+	buf := make([]byte, n+n/2+1)
+	for count := 0; count < n; {
+		if _, err := crand.Read(buf); err != nil {
+			panic(err)
+		}
+		for _, b := range buf {
+			if idx := int(b & mask); idx < len(chars) {
+				out = utf8.AppendRune(out, chars[idx])
+				if count++; count == n {
+					break
+				}
+			}
+		}
+	}
+	return string(out)
+}
+
+// RandToken 返回nBytes个随机字节的URL安全base64编码(无padding)，nBytes<=0时使用RANDTOKENBYTES
+func RandToken(nBytes int) string {
+	if nBytes <= 0 {
+		nBytes = RANDTOKENBYTES
+	}
+	return base64.RawURLEncoding.EncodeToString(RandBytes(nBytes))
+}
@@ -0,0 +1,36 @@
+package common
+
+import (
+	"strings"
+	"testing"
+	"unicode/utf8"
+)
+
+func TestRandString(t *testing.T) {
+	for _, alphabet := range []string{AlphabetDigits, AlphabetAlphaNum, "x", "αβγ"} {
+		s := RandString(50, alphabet)
+		if utf8.RuneCountInString(s) != 50 {
+			t.Fatalf("%q: length %d", alphabet, utf8.RuneCountInString(s))
+		}
+		for _, r := range s {
+			if !strings.ContainsRune(alphabet, r) {
+				t.Fatalf("%q: unexpected char %q", alphabet, r)
+			}
+		}
+	}
+
+	// 10个字符的字母表下每个字符都应出现，粗略检查没有取模偏差导致的缺失
+	seen := map[rune]int{}
+	for _, r := range RandString(10000, AlphabetDigits) {
+		seen[r]++
+	}
+	for _, r := range AlphabetDigits {
+		if seen[r] < 800 {
+			t.Fatalf("digit %q appeared only %d times", r, seen[r])
+		}
+	}
+
+	if tok := RandToken(0); len(tok) != 43 || strings.ContainsAny(tok, "+/=") {
+		t.Fatalf("token %q", tok)
+	}
+}
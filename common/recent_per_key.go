@@ -0,0 +1,121 @@
+package common
+
+import (
+	"time"
+)
+
+// RecentItem RecentPerKey中保存的值及其写入时间
+type RecentItem[V any] struct {
+	Value V
+	At    time.Time
+}
+
+// recentRing 固定容量的环形缓冲，满时覆盖最早的值
+type recentRing[V any] struct {
+	items []RecentItem[V]
+	head  int
+	n     int
+}
+
+func (r *recentRing[V]) push(item RecentItem[V]) {
+	if r.n == len(r.items) {
+		r.items[r.head] = item
+		r.head = (r.head + 1) % len(r.items)
+		return
+	}
+	r.items[(r.head+r.n)%len(r.items)] = item
+	r.n++
+}
+
+// evict 丢弃写入时间早于deadline的值
+func (r *recentRing[V]) evict(deadline time.Time) {
+	var zero RecentItem[V]
+	for r.n > 0 && r.items[r.head].At.Before(deadline) {
+		r.items[r.head] = zero
+		r.head = (r.head + 1) % len(r.items)
+		r.n--
+	}
+}
+
+// newestFirst 按写入时间倒序返回
+func (r *recentRing[V]) newestFirst() []RecentItem[V] {
+	items := make([]RecentItem[V], r.n)
+	for i := range items {
+		items[i] = r.items[(r.head+r.n-1-i)%len(r.items)]
+	}
+	return items
+}
+
+// RecentPerKey 按key保存最近n个值，值在ttl后过期，用于"查看某symbol最近处理的10条消息"等调试接口
+type RecentPerKey[K comparable, V any] struct {
+	shards SyncMapGroup[K, *recentRing[V]]
+	n      int
+	ttl    time.Duration
+}
+
+// NewRecentPerKey shards需为2的幂，ttl<=0表示不过期
+func NewRecentPerKey[K comparable, V any](shards, n int, ttl time.Duration) *RecentPerKey[K, V] {
+	return &RecentPerKey[K, V]{
+		shards: NewSyncMapGroup[K, *recentRing[V]](shards, 64),
+		n:      Max(n, 1),
+		ttl:    ttl,
+	}
+}
+
+func (rk *RecentPerKey[K, V]) Add(k K, v V) {
+	now := time.Now()
+	rk.shards.ShardFor(k).Compute(k, func(old *recentRing[V], loaded bool) (*recentRing[V], bool) {
+		if !loaded {
+			old = &recentRing[V]{items: make([]RecentItem[V], rk.n)}
+		}
+		rk.evict(old, now)
+		old.push(RecentItem[V]{Value: v, At: now})
+		return old, true
+	})
+}
+
+// Recent 返回k最近的未过期值，最新的在前
+func (rk *RecentPerKey[K, V]) Recent(k K) []RecentItem[V] {
+	var items []RecentItem[V]
+	rk.shards.ShardFor(k).Compute(k, func(old *recentRing[V], loaded bool) (*recentRing[V], bool) {
+		if !loaded {
+			return nil, false
+		}
+		rk.evict(old, time.Now())
+		items = old.newestFirst()
+		return old, old.n > 0
+	})
+	return items
+}
+
+func (rk *RecentPerKey[K, V]) Remove(k K) {
+	rk.shards.ShardFor(k).Delete(k)
+}
+
+// Keys 返回当前保存的key，可能包含值已过期但尚未清理的key
+func (rk *RecentPerKey[K, V]) Keys() []K {
+	var keys []K
+	for _, shard := range rk.shards {
+		shard.Range(func(k K, _ *recentRing[V]) bool {
+			keys = append(keys, k)
+			return true
+		})
+	}
+	return keys
+}
+
+// Sweep 清理值已全部过期的key，可由使用方定期调用
+func (rk *RecentPerKey[K, V]) Sweep() {
+	if rk.ttl <= 0 {
+		return
+	}
+	for _, k := range rk.Keys() {
+		rk.Recent(k)
+	}
+}
+
+func (rk *RecentPerKey[K, V]) evict(r *recentRing[V], now time.Time) {
+	if rk.ttl > 0 {
+		r.evict(now.Add(-rk.ttl))
+	}
+}
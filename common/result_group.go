@@ -0,0 +1,38 @@
+package common
+
+import (
+	"sync"
+)
+
+// ResultGroup 收集各任务结果的TaskGroup，零值可用
+type ResultGroup[T any] struct {
+	tg      TaskGroup
+	mu      sync.Mutex
+	results []T
+}
+
+// Go 异步执行f，结果按提交顺序保存
+func (rg *ResultGroup[T]) Go(f func() (T, error)) *ResultGroup[T] {
+	rg.mu.Lock()
+	idx := len(rg.results)
+	var zero T
+	rg.results = append(rg.results, zero)
+	rg.mu.Unlock()
+
+	rg.tg.Go(func() error {
+		v, err := f()
+		rg.mu.Lock()
+		rg.results[idx] = v
+		rg.mu.Unlock()
+		return err
+	})
+	return rg
+}
+
+// Wait 等待所有任务结束，results与Go的调用顺序一一对应，失败任务的结果为f返回的值
+func (rg *ResultGroup[T]) Wait() ([]T, error) {
+	err := rg.tg.Wait()
+	rg.mu.Lock()
+	defer rg.mu.Unlock()
+	return rg.results, err
+}
@@ -0,0 +1,92 @@
+package common
+
+import (
+	"sync"
+)
+
+// RingMode RingBuffer写满时的行为
+type RingMode int
+
+const (
+	RingOverwrite RingMode = iota // 覆盖最早的值
+	RingReject                    // 拒绝写入
+)
+
+// RingBuffer 固定容量的环形缓冲，并发安全，用于保留最近N条消息、错误等
+type RingBuffer[T any] struct {
+	mu    sync.Mutex
+	items []T
+	head  int // 最早值的下标
+	n     int
+	mode  RingMode
+}
+
+func NewRingBuffer[T any](capacity int, mode RingMode) *RingBuffer[T] {
+	return &RingBuffer[T]{
+		items: make([]T, Max(capacity, 1)),
+		mode:  mode,
+	}
+}
+
+// Push 写入v，RingReject模式下缓冲已满时返回false
+func (rb *RingBuffer[T]) Push(v T) bool {
+	rb.mu.Lock()
+	defer rb.mu.Unlock()
+
+	if rb.n == len(rb.items) {
+		if rb.mode == RingReject {
+			return false
+		}
+		rb.items[rb.head] = v
+		rb.head = (rb.head + 1) % len(rb.items)
+		return true
+	}
+	rb.items[(rb.head+rb.n)%len(rb.items)] = v
+	rb.n++
+	return true
+}
+
+// Pop 取出最早的值
+func (rb *RingBuffer[T]) Pop() (v T, ok bool) {
+	rb.mu.Lock()
+	defer rb.mu.Unlock()
+
+	if rb.n == 0 {
+		return
+	}
+	var zero T
+	v, rb.items[rb.head] = rb.items[rb.head], zero
+	rb.head = (rb.head + 1) % len(rb.items)
+	rb.n--
+	return v, true
+}
+
+// Snapshot 按写入顺序返回当前所有值，最早的在前
+func (rb *RingBuffer[T]) Snapshot() []T {
+	rb.mu.Lock()
+	defer rb.mu.Unlock()
+
+	items := make([]T, rb.n)
+	for i := range items {
+		items[i] = rb.items[(rb.head+i)%len(rb.items)]
+	}
+	return items
+}
+
+func (rb *RingBuffer[T]) Len() int {
+	rb.mu.Lock()
+	defer rb.mu.Unlock()
+	return rb.n
+}
+
+func (rb *RingBuffer[T]) Cap() int {
+	return len(rb.items)
+}
+
+// Clear 清空缓冲，释放对值的引用
+func (rb *RingBuffer[T]) Clear() {
+	rb.mu.Lock()
+	defer rb.mu.Unlock()
+	clear(rb.items)
+	rb.head, rb.n = 0, 0
+}
@@ -0,0 +1,35 @@
+package common
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestRingBuffer(t *testing.T) {
+	rb := NewRingBuffer[int](3, RingOverwrite)
+	for i := 1; i <= 5; i++ {
+		rb.Push(i)
+	}
+	if got := rb.Snapshot(); !slices.Equal(got, []int{3, 4, 5}) {
+		t.Fatalf("overwrite snapshot %v", got)
+	}
+	if v, ok := rb.Pop(); !ok || v != 3 {
+		t.Fatalf("pop %d %v", v, ok)
+	}
+	rb.Push(6)
+	if got := rb.Snapshot(); !slices.Equal(got, []int{4, 5, 6}) {
+		t.Fatalf("snapshot after pop %v", got)
+	}
+
+	rj := NewRingBuffer[int](2, RingReject)
+	if !rj.Push(1) || !rj.Push(2) || rj.Push(3) {
+		t.Fatal("reject mode accepted a push into a full buffer")
+	}
+	if got := rj.Snapshot(); !slices.Equal(got, []int{1, 2}) {
+		t.Fatalf("reject snapshot %v", got)
+	}
+	rj.Clear()
+	if rj.Len() != 0 {
+		t.Fatal("clear")
+	}
+}
@@ -0,0 +1,87 @@
+package common
+
+import (
+	"math"
+	"math/big"
+	"strconv"
+	"unsafe"
+
+	"golang.org/x/exp/constraints"
+)
+
+// RoundTo 四舍五入到places位小数(.5远离0)，places为负时舍入到整十、整百等。
+// 按v的最短十进制表示计算，RoundTo(1.005, 2)得到1.01而不是受二进制误差影响的1.00
+func RoundTo[T constraints.Float](v T, places int) T {
+	return roundFloat(v, placesStep(places), RoundHalfUp)
+}
+
+// FloorTo 向负无穷舍入到places位小数
+func FloorTo[T constraints.Float](v T, places int) T {
+	return roundFloat(v, placesStep(places), RoundFloor)
+}
+
+// CeilTo 向正无穷舍入到places位小数
+func CeilTo[T constraints.Float](v T, places int) T {
+	return roundFloat(v, placesStep(places), RoundCeil)
+}
+
+// RoundToStep 四舍五入到step的整数倍，如按最小变动价位0.05取整；step<=0时原样返回
+func RoundToStep[T constraints.Float](v, step T) T {
+	return roundFloatStep(v, step, RoundHalfUp)
+}
+
+// FloorToStep 向负无穷舍入到step的整数倍
+func FloorToStep[T constraints.Float](v, step T) T {
+	return roundFloatStep(v, step, RoundFloor)
+}
+
+// CeilToStep 向正无穷舍入到step的整数倍
+func CeilToStep[T constraints.Float](v, step T) T {
+	return roundFloatStep(v, step, RoundCeil)
+}
+
+func roundFloatStep[T constraints.Float](v, step T, mode RoundingMode) T {
+	if !(step > 0) || math.IsInf(float64(step), 0) {
+		return v
+	}
+	return roundFloat(v, floatRat(step), mode)
+}
+
+// roundFloat 将v舍入到step的整数倍，用有理数精确计算，大数值和负数不受浮点误差影响
+func roundFloat[T constraints.Float](v T, step *big.Rat, mode RoundingMode) T {
+	if math.IsNaN(float64(v)) || math.IsInf(float64(v), 0) {
+		return v
+	}
+	// v/step = (a/b)/(c/d) = a*d/(b*c)
+	r := floatRat(v)
+	num := new(big.Int).Mul(r.Num(), step.Denom())
+	den := new(big.Int).Mul(r.Denom(), step.Num())
+	q := roundQuo(num, den, mode)
+
+	r.SetFrac(q.Mul(q, step.Num()), step.Denom())
+	if floatBits[T]() == 32 {
+		f, _ := r.Float32()
+		return T(f)
+	}
+	f, _ := r.Float64()
+	return T(f)
+}
+
+// placesStep 10^-places
+func placesStep(places int) *big.Rat {
+	if places >= 0 {
+		return new(big.Rat).SetFrac(big.NewInt(1), pow10(places))
+	}
+	return new(big.Rat).SetInt(pow10(-places))
+}
+
+// floatRat v的最短十进制表示对应的有理数
+func floatRat[T constraints.Float](v T) *big.Rat {
+	r, _ := new(big.Rat).SetString(strconv.FormatFloat(float64(v), 'g', -1, floatBits[T]()))
+	return r
+}
+
+func floatBits[T constraints.Float]() int {
+	var v T
+	return int(unsafe.Sizeof(v)) * 8
+}
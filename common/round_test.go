@@ -0,0 +1,36 @@
+package common
+
+import (
+	"testing"
+)
+
+func TestRoundTo(t *testing.T) {
+	cases := []struct {
+		name string
+		got  float64
+		want float64
+	}{
+		{"half up", RoundTo(1.005, 2), 1.01},
+		{"negative half", RoundTo(-1.005, 2), -1.01},
+		{"negative places", RoundTo(1250.0, -2), 1300},
+		{"floor negative", FloorTo(-2.341, 2), -2.35},
+		{"ceil negative", CeilTo(-2.349, 2), -2.34},
+		{"floor exact", FloorTo(0.3, 1), 0.3},
+		{"step", RoundToStep(1.024, 0.05), 1.0},
+		{"step half", RoundToStep(1.025, 0.05), 1.05},
+		{"floor step", FloorToStep(-1.01, 0.05), -1.05},
+		{"ceil step", CeilToStep(101.26, 0.25), 101.5},
+		{"large", RoundTo(1e20+0.5, 2), 1e20},
+		{"large step", FloorToStep(123456789012345.67, 0.01), 123456789012345.67},
+		{"zero step", RoundToStep(1.234, 0), 1.234},
+	}
+	for _, c := range cases {
+		if c.got != c.want {
+			t.Errorf("%s: got %v, want %v", c.name, c.got, c.want)
+		}
+	}
+
+	if got := RoundTo(float32(2.675), 2); got != float32(2.68) {
+		t.Errorf("float32: got %v", got)
+	}
+}
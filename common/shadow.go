@@ -0,0 +1,135 @@
+package common
+
+import (
+	"sync/atomic"
+)
+
+type ShadowOption[I, O any] func(*Shadow[I, O])
+
+// WithShadowRate 按rate比例抽样执行候选实现，默认全部执行
+func WithShadowRate[I, O any](rate float64, r Rand) ShadowOption[I, O] {
+	return func(s *Shadow[I, O]) {
+		s.rate = rate
+		if r != nil {
+			s.rand = r
+		}
+	}
+}
+
+// WithOnMismatch 输出不一致或只有一方返回错误时回调
+func WithOnMismatch[I, O any](fn func(in I, primary, candidate O, primaryErr, candidateErr error)) ShadowOption[I, O] {
+	return func(s *Shadow[I, O]) {
+		s.onMismatch = fn
+	}
+}
+
+// WithShadowSync 在调用方goroutine中同步执行候选实现，默认异步执行以免影响主流程耗时
+func WithShadowSync[I, O any]() ShadowOption[I, O] {
+	return func(s *Shadow[I, O]) {
+		s.sync = true
+	}
+}
+
+// ShadowStats 对比统计
+type ShadowStats struct {
+	Sampled    uint64 // 执行了候选实现的次数
+	Matched    uint64
+	Mismatched uint64
+	Panics     uint64 // 候选实现panic的次数，计入Mismatched
+}
+
+// MismatchRate 不一致比例
+func (s ShadowStats) MismatchRate() float64 {
+	if s.Sampled == 0 {
+		return 0
+	}
+	return float64(s.Mismatched) / float64(s.Sampled)
+}
+
+// Shadow 在相同输入上运行新旧两种实现并对比结果，调用方始终拿到primary的结果，
+// 用于安全迁移kafka handler、SyncedData加载函数等逻辑
+type Shadow[I, O any] struct {
+	primary    func(I) (O, error)
+	candidate  func(I) (O, error)
+	equal      func(a, b O) bool
+	rate       float64
+	rand       Rand
+	sync       bool
+	onMismatch func(in I, primary, candidate O, primaryErr, candidateErr error)
+
+	sampled    atomic.Uint64
+	matched    atomic.Uint64
+	mismatched atomic.Uint64
+	panics     atomic.Uint64
+}
+
+func NewShadow[I, O any](primary, candidate func(I) (O, error), equal func(a, b O) bool, opts ...ShadowOption[I, O]) *Shadow[I, O] {
+	s := &Shadow[I, O]{
+		primary:   primary,
+		candidate: candidate,
+		equal:     equal,
+		rate:      1,
+		rand:      DefaultRand,
+	}
+	for _, opt := range opts {
+		opt(s)
+	}
+	return s
+}
+
+// Call 执行primary并返回其结果，抽样命中时同时执行candidate对比
+func (s *Shadow[I, O]) Call(in I) (O, error) {
+	out, err := s.primary(in)
+	if Sample(s.rand, s.rate) {
+		if s.sync {
+			s.compare(in, out, err)
+		} else {
+			go s.compare(in, out, err)
+		}
+	}
+	return out, err
+}
+
+func (s *Shadow[I, O]) compare(in I, out O, err error) {
+	s.sampled.Add(1)
+
+	var cand O
+	cerr := safeCall(func() (e error) {
+		cand, e = s.candidate(in)
+		return
+	})
+	if _, ok := cerr.(*PanicError); ok {
+		s.panics.Add(1)
+	}
+
+	match := (err == nil) == (cerr == nil)
+	if match && err == nil {
+		match = s.equal(out, cand)
+	}
+	if match {
+		s.matched.Add(1)
+		return
+	}
+	s.mismatched.Add(1)
+	if s.onMismatch != nil {
+		s.onMismatch(in, out, cand, err, cerr)
+	}
+}
+
+func (s *Shadow[I, O]) Stats() ShadowStats {
+	return ShadowStats{
+		Sampled:    s.sampled.Load(),
+		Matched:    s.matched.Load(),
+		Mismatched: s.mismatched.Load(),
+		Panics:     s.panics.Load(),
+	}
+}
+
+// RegisterGauges 将对比统计注册到g，指标名以prefix开头
+func (s *Shadow[I, O]) RegisterGauges(g *Gauges, prefix string) error {
+	return g.RegisterAll(prefix, []GaugeFunc{
+		{"_sampled", "shadow comparisons executed", func() float64 { return float64(s.sampled.Load()) }},
+		{"_mismatched", "shadow comparisons with different results", func() float64 { return float64(s.mismatched.Load()) }},
+		{"_mismatch_rate", "ratio of mismatched shadow comparisons", func() float64 { return s.Stats().MismatchRate() }},
+	})
+}
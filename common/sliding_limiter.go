@@ -0,0 +1,70 @@
+package common
+
+import (
+	"sync"
+	"time"
+)
+
+// SlidingLimiter 按key的滑动窗口限流，每个key在任意interval内最多放行limit次。
+// 与TriggerWindow共用时间戳环形缓冲，语义相反：TriggerWindow判断是否达到告警阈值，SlidingLimiter判断是否还能放行
+type SlidingLimiter[T comparable] struct {
+	mu       *sync.Mutex
+	records  map[T]*timeRing
+	interval time.Duration
+	limit    int
+}
+
+func NewSlidingLimiter[T comparable](limit int, interval time.Duration) *SlidingLimiter[T] {
+	return &SlidingLimiter[T]{
+		mu:       &sync.Mutex{},
+		limit:    limit,
+		interval: interval,
+		records:  make(map[T]*timeRing, 128),
+	}
+}
+
+// Allow 窗口内放行次数未达到limit时记录本次并返回true，否则返回false且不记录
+func (sl *SlidingLimiter[T]) Allow(key T) bool {
+	sl.mu.Lock()
+	defer sl.mu.Unlock()
+
+	now := time.Now()
+	ring, exists := sl.records[key]
+	if !exists {
+		ring = newTimeRing(sl.limit)
+		sl.records[key] = ring
+	}
+	ring.evict(now, sl.interval)
+	if ring.n >= sl.limit {
+		return false
+	}
+	ring.push(now)
+	return true
+}
+
+// Remaining 返回key在当前窗口内还能放行的次数
+func (sl *SlidingLimiter[T]) Remaining(key T) int {
+	sl.mu.Lock()
+	defer sl.mu.Unlock()
+	ring, exists := sl.records[key]
+	if !exists {
+		return sl.limit
+	}
+	return Max(sl.limit-ring.count(time.Now(), sl.interval), 0)
+}
+
+// Sweep 清理最后一次放行距今超过interval的key，可由使用方定期调用
+func (sl *SlidingLimiter[T]) Sweep() int {
+	sl.mu.Lock()
+	defer sl.mu.Unlock()
+
+	now := time.Now()
+	removed := 0
+	for key, ring := range sl.records {
+		if now.Sub(ring.last) > sl.interval {
+			delete(sl.records, key)
+			removed++
+		}
+	}
+	return removed
+}
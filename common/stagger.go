@@ -0,0 +1,54 @@
+package common
+
+import (
+	"context"
+	"time"
+
+	"go.uber.org/multierr"
+)
+
+// Stagger 在spread时间窗口内均匀地依次调用fn(0..n-1)，避免大量组件同时启动冲击上游
+func Stagger(ctx context.Context, n int, spread time.Duration, fn func(i int) error) error {
+	return stagger(ctx, n, spread, nil, fn)
+}
+
+// StaggerWithJitter 同Stagger，但每次调用时间在各自的时间片内随机
+func StaggerWithJitter(ctx context.Context, n int, spread time.Duration, fn func(i int) error) error {
+	return stagger(ctx, n, spread, DefaultRand, fn)
+}
+
+// StaggerWithRand 同StaggerWithJitter，使用指定的随机源
+func StaggerWithRand(ctx context.Context, n int, spread time.Duration, r Rand, fn func(i int) error) error {
+	return stagger(ctx, n, spread, r, fn)
+}
+
+func stagger(ctx context.Context, n int, spread time.Duration, r Rand, fn func(i int) error) (err error) {
+	if n <= 0 {
+		return nil
+	}
+
+	slot := spread / time.Duration(n)
+	start := time.Now()
+	timer := time.NewTimer(0)
+	defer timer.Stop()
+	<-timer.C
+
+	for i := 0; i < n; i++ {
+		offset := slot * time.Duration(i)
+		if r != nil {
+			offset += RandDuration(r, slot)
+		}
+		if wait := time.Until(start.Add(offset)); wait > 0 {
+			timer.Reset(wait)
+			select {
+			case <-ctx.Done():
+				return multierr.Append(err, ctx.Err())
+			case <-timer.C:
+			}
+		} else if ctx.Err() != nil {
+			return multierr.Append(err, ctx.Err())
+		}
+		err = multierr.Append(err, fn(i))
+	}
+	return
+}
@@ -0,0 +1,147 @@
+package common
+
+import (
+	"math"
+	"slices"
+	"sync"
+
+	"golang.org/x/exp/constraints"
+)
+
+type Number interface {
+	constraints.Integer | constraints.Float
+}
+
+// Mean 算术平均值，values为空时返回0
+func Mean[T Number](values []T) float64 {
+	if len(values) == 0 {
+		return 0
+	}
+	var sum float64
+	for _, v := range values {
+		sum += float64(v)
+	}
+	return sum / float64(len(values))
+}
+
+// StdDev 总体标准差，values为空时返回0
+func StdDev[T Number](values []T) float64 {
+	if len(values) == 0 {
+		return 0
+	}
+	mean := Mean(values)
+	var sq float64
+	for _, v := range values {
+		d := float64(v) - mean
+		sq += d * d
+	}
+	return math.Sqrt(sq / float64(len(values)))
+}
+
+// Percentile 第p(0~100)百分位数，相邻样本间线性插值，不修改values
+func Percentile[T Number](values []T, p float64) float64 {
+	if len(values) == 0 {
+		return 0
+	}
+	sorted := slices.Clone(values)
+	slices.Sort(sorted)
+	return percentileSorted(sorted, p)
+}
+
+func percentileSorted[T Number](sorted []T, p float64) float64 {
+	p = Min(Max(p, 0), 100)
+	pos := p / 100 * float64(len(sorted)-1)
+	lo := int(pos)
+	if lo >= len(sorted)-1 {
+		return float64(sorted[len(sorted)-1])
+	}
+	frac := pos - float64(lo)
+	return float64(sorted[lo])*(1-frac) + float64(sorted[lo+1])*frac
+}
+
+// EWMA 指数加权移动平均，alpha越大越偏重新样本，可并发调用
+type EWMA struct {
+	mu    sync.Mutex
+	alpha float64
+	value float64
+	init  bool
+}
+
+func NewEWMA(alpha float64) *EWMA {
+	return &EWMA{alpha: alpha}
+}
+
+// Add 第一个样本直接作为初始值
+func (e *EWMA) Add(v float64) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if !e.init {
+		e.value, e.init = v, true
+		return
+	}
+	e.value += e.alpha * (v - e.value)
+}
+
+func (e *EWMA) Value() float64 {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return e.value
+}
+
+// RollingStats 保留最近n个样本的统计，可并发调用
+type RollingStats[T Number] struct {
+	mu      sync.Mutex
+	samples []T
+	next    int
+	full    bool
+}
+
+func NewRollingStats[T Number](n int) *RollingStats[T] {
+	return &RollingStats[T]{samples: make([]T, Max(n, 1))}
+}
+
+func (rs *RollingStats[T]) Add(v T) {
+	rs.mu.Lock()
+	defer rs.mu.Unlock()
+	rs.samples[rs.next] = v
+	rs.next = (rs.next + 1) % len(rs.samples)
+	if rs.next == 0 {
+		rs.full = true
+	}
+}
+
+// Values 返回当前窗口内样本的副本(顺序不保证)
+func (rs *RollingStats[T]) Values() []T {
+	rs.mu.Lock()
+	defer rs.mu.Unlock()
+	if rs.full {
+		return slices.Clone(rs.samples)
+	}
+	return slices.Clone(rs.samples[:rs.next])
+}
+
+func (rs *RollingStats[T]) Count() int {
+	rs.mu.Lock()
+	defer rs.mu.Unlock()
+	if rs.full {
+		return len(rs.samples)
+	}
+	return rs.next
+}
+
+func (rs *RollingStats[T]) Mean() float64 {
+	return Mean(rs.Values())
+}
+
+func (rs *RollingStats[T]) StdDev() float64 {
+	return StdDev(rs.Values())
+}
+
+func (rs *RollingStats[T]) Percentile(p float64) float64 {
+	values := rs.Values()
+	if len(values) == 0 {
+		return 0
+	}
+	slices.Sort(values)
+	return percentileSorted(values, p)
+}
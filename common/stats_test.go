@@ -0,0 +1,34 @@
+package common
+
+import (
+	"math"
+	"testing"
+)
+
+func TestStats(t *testing.T) {
+	values := []int{2, 4, 4, 4, 5, 5, 7, 9}
+	if Mean(values) != 5 || StdDev(values) != 2 {
+		t.Fatalf("mean %v stddev %v", Mean(values), StdDev(values))
+	}
+	if p := Percentile([]float64{1, 2, 3, 4}, 50); p != 2.5 {
+		t.Fatalf("p50 %v", p)
+	}
+	if p := Percentile(values, 100); p != 9 {
+		t.Fatalf("p100 %v", p)
+	}
+
+	rs := NewRollingStats[int](3)
+	for _, v := range []int{100, 1, 2, 3} {
+		rs.Add(v)
+	}
+	if rs.Count() != 3 || rs.Mean() != 2 {
+		t.Fatalf("rolling count %d mean %v", rs.Count(), rs.Mean())
+	}
+
+	e := NewEWMA(0.5)
+	e.Add(10)
+	e.Add(20)
+	if math.Abs(e.Value()-15) > 1e-9 {
+		t.Fatalf("ewma %v", e.Value())
+	}
+}
@@ -0,0 +1,46 @@
+package common
+
+import (
+	"sync"
+)
+
+// StreamGroup 任务完成即通过Results投递结果的TaskGroup，适合流水线式消费
+type StreamGroup[T any] struct {
+	tg        TaskGroup
+	results   chan T
+	closeOnce sync.Once
+}
+
+// NewStreamGroup buffer为结果channel的缓冲大小，消费不及时时任务会阻塞在投递上
+func NewStreamGroup[T any](buffer int) *StreamGroup[T] {
+	return &StreamGroup[T]{
+		results: make(chan T, buffer),
+	}
+}
+
+// GoStream 异步执行f，成功的结果按完成顺序写入Results，错误聚合到Wait的返回值
+func (sg *StreamGroup[T]) GoStream(f func() (T, error)) *StreamGroup[T] {
+	sg.tg.Go(func() error {
+		v, err := f()
+		if err != nil {
+			return err
+		}
+		sg.results <- v
+		return nil
+	})
+	return sg
+}
+
+// Results 所有任务结束且调用Wait后关闭
+func (sg *StreamGroup[T]) Results() <-chan T {
+	return sg.results
+}
+
+// Wait 等待所有任务结束后关闭Results；需与消费Results的goroutine并发调用
+func (sg *StreamGroup[T]) Wait() error {
+	err := sg.tg.Wait()
+	sg.closeOnce.Do(func() {
+		close(sg.results)
+	})
+	return err
+}
@@ -8,10 +8,12 @@ import (
 	"sync"
 	"sync/atomic"
 	"time"
+
+	"github.com/cdpzyafk/go-utils/optkit"
 )
 
 // 定义可配置的选项（通过函数选项模式增强扩展性）
-type SyncedDataOption[T any] func(*SyncedData[T])
+type SyncedDataOption[T any] = optkit.Option[SyncedData[T]]
 
 // WithDefaultValue 设置默认值（f() 失败时 Get() 返回该值）
 func WithDefaultValue[T any](val T) SyncedDataOption[T] {
@@ -40,6 +42,13 @@ func WithRetryPolicy[T any](maxRetries int, retryInterval time.Duration) SyncedD
 	}
 }
 
+// WithRetryBackoff 重试间隔按b退避，代替WithRetryPolicy的固定间隔
+func WithRetryBackoff[T any](b *Backoff) SyncedDataOption[T] {
+	return func(sd *SyncedData[T]) {
+		sd.retryBackoff = b
+	}
+}
+
 // WithImmediateRefresh 初始化时是否立即执行一次刷新（默认 true，与原逻辑一致）
 func WithImmediateRefresh[T any](immediate bool) SyncedDataOption[T] {
 	return func(sd *SyncedData[T]) {
@@ -47,6 +56,20 @@ func WithImmediateRefresh[T any](immediate bool) SyncedDataOption[T] {
 	}
 }
 
+// WithHeartbeat 每次刷新成功时向h报告name的心跳
+func WithHeartbeat[T any](h *Heartbeat, name string) SyncedDataOption[T] {
+	return func(sd *SyncedData[T]) {
+		sd.heartbeat, sd.heartbeatName = h, name
+	}
+}
+
+// WithAlignedRefresh 在墙钟的刷新间隔整数倍时刻刷新（如每分钟整点），多实例刷新时间一致
+func WithAlignedRefresh[T any]() SyncedDataOption[T] {
+	return func(sd *SyncedData[T]) {
+		sd.aligned = true
+	}
+}
+
 type SyncedData[T any] struct {
 	d                *atomic.Value     // 存储核心数据
 	f                func() (T, error) // 数据刷新函数
@@ -55,7 +78,9 @@ type SyncedData[T any] struct {
 	logger           *log.Logger       // 日志器
 	retryMax         int               // 最大重试次数
 	retryInterval    time.Duration     // 重试间隔
+	retryBackoff     *Backoff          // 重试退避，默认按retryInterval固定间隔
 	immediateRefresh bool              // 初始化时是否立即刷新
+	aligned          bool              // 按墙钟整点刷新
 
 	initDone        atomic.Bool        // 初始化完成标志（确保 Init 仅执行一次）
 	ctx             context.Context    // 管理 Goroutine 生命周期
@@ -64,19 +89,13 @@ type SyncedData[T any] struct {
 	runningMu       sync.Mutex         // 防止 f() 并发执行
 	lastRefreshTime atomic.Value       // 最后一次刷新时间（time.Time）
 	lastRefreshOk   atomic.Bool        // 最后一次刷新是否成功
+	heartbeat       *Heartbeat         // 刷新成功时报告心跳
+	heartbeatName   string
 }
 
 // NewSyncedData 创建 SyncedData 实例（新增参数校验和选项配置）
 func NewSyncedData[T any](t time.Duration, f func() (T, error), opts ...SyncedDataOption[T]) (*SyncedData[T], error) {
-	// 1. 校验核心参数合法性
-	if t <= 0 {
-		return nil, fmt.Errorf("refresh interval must be positive: %v", t)
-	}
-	if f == nil {
-		return nil, errors.New("refresh function f cannot be nil")
-	}
-
-	// 2. 初始化默认值
+	// 1. 初始化默认值
 	ctx, cancel := context.WithCancel(context.Background())
 	sd := &SyncedData[T]{
 		d:                &atomic.Value{},
@@ -90,18 +109,33 @@ func NewSyncedData[T any](t time.Duration, f func() (T, error), opts ...SyncedDa
 		cancel:           cancel,
 	}
 
-	// 3. 应用用户配置选项
-	for _, opt := range opts {
-		opt(sd)
+	// 2. 应用用户配置选项并校验参数合法性
+	if err := optkit.ApplyTo(sd, opts...); err != nil {
+		cancel()
+		return nil, err
 	}
 
-	// 4. 初始化状态字段
+	// 3. 初始化状态字段
+	if sd.retryBackoff == nil {
+		sd.retryBackoff = NewBackoff(sd.retryInterval, sd.retryInterval)
+	}
 	sd.lastRefreshTime.Store(time.Time{})
 	sd.lastRefreshOk.Store(false)
 
 	return sd, nil
 }
 
+// Validate 校验核心参数合法性（由 optkit.ApplyTo 在应用选项后调用）
+func (c *SyncedData[T]) Validate() error {
+	if c.t <= 0 {
+		return fmt.Errorf("refresh interval must be positive: %v", c.t)
+	}
+	if c.f == nil {
+		return errors.New("refresh function f cannot be nil")
+	}
+	return nil
+}
+
 // Get 获取数据（返回 (T, error) 避免 Panic，支持默认值兜底）
 func (c *SyncedData[T]) Get() (T, error) {
 	// 1. 检查是否初始化
@@ -126,8 +160,7 @@ func (c *SyncedData[T]) Set(v T) error {
 		return errors.New("cannot set data before initialization")
 	}
 	c.d.Store(v)
-	c.lastRefreshTime.Store(time.Now())
-	c.lastRefreshOk.Store(true)
+	c.markRefreshed()
 	return nil
 }
 
@@ -169,15 +202,23 @@ func (c *SyncedData[T]) refreshLoop() {
 	defer c.wg.Done()
 
 	// 初始化定时器（首次刷新后开始计时）
-	ticker := time.NewTicker(c.t)
-	defer ticker.Stop()
+	var tick <-chan time.Time
+	if c.aligned {
+		ticker := NewAlignedTicker(c.t)
+		defer ticker.Stop()
+		tick = ticker.C
+	} else {
+		ticker := time.NewTicker(c.t)
+		defer ticker.Stop()
+		tick = ticker.C
+	}
 
 	for {
 		select {
 		case <-c.ctx.Done():
 			c.logger.Println("refresh loop exiting...")
 			return
-		case <-ticker.C:
+		case <-tick:
 			// 避免 f() 并发执行（加锁）
 			c.runningMu.Lock()
 			if err := c.refreshWithRetry(); err != nil {
@@ -196,6 +237,7 @@ func (c *SyncedData[T]) refreshWithRetry() error {
 	)
 
 	// 执行刷新（带重试）
+	c.retryBackoff.Reset()
 	for attempt := 0; attempt <= c.retryMax; attempt++ {
 		data, err = c.f()
 		if err == nil {
@@ -208,14 +250,23 @@ func (c *SyncedData[T]) refreshWithRetry() error {
 			return fmt.Errorf("refresh failed after %d attempts: %v", c.retryMax+1, err)
 		}
 
-		c.logger.Printf("refresh attempt %d failed: %v, retry in %v", attempt+1, err, c.retryInterval)
-		time.Sleep(c.retryInterval)
+		wait := c.retryBackoff.Next()
+		c.logger.Printf("refresh attempt %d failed: %v, retry in %v", attempt+1, err, wait)
+		time.Sleep(wait)
 	}
 
 	// 刷新成功：更新数据和状态
 	c.d.Store(data)
-	c.lastRefreshTime.Store(time.Now())
-	c.lastRefreshOk.Store(true)
+	c.markRefreshed()
 	c.logger.Printf("refresh success, updated data at %v", c.lastRefreshTime.Load().(time.Time))
 	return nil
 }
+
+// markRefreshed 记录一次成功更新并报告心跳；lastRefreshOk还记录最近一次刷新是否失败，心跳只反映成功
+func (c *SyncedData[T]) markRefreshed() {
+	c.lastRefreshTime.Store(time.Now())
+	c.lastRefreshOk.Store(true)
+	if c.heartbeat != nil {
+		c.heartbeat.Beat(c.heartbeatName)
+	}
+}
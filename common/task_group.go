@@ -2,62 +2,497 @@ package common
 
 import (
 	"context"
+	"errors"
+	"fmt"
+	"runtime/debug"
 	"sync"
+	"sync/atomic"
+	"time"
 
 	"go.uber.org/multierr"
 	"golang.org/x/sync/semaphore"
 )
 
+const (
+	MAXTASKWEIGHT = 1 << 30 // WeightedTaskGroup可Resize到的最大权重
+)
+
+var (
+	ErrTaskTimeout = errors.New("task timeout")
+	ErrQueueFull   = errors.New("task queue full")
+)
+
 type TaskGroup struct {
 	err   error
 	wg    sync.WaitGroup
 	mutex sync.Mutex
+
+	ctx    context.Context
+	cancel context.CancelFunc
+
+	repanic bool
+	panic   *PanicError
+
+	firstErr error
+	failed   chan struct{} // 第一个错误发生时关闭，惰性创建
+
+	submitted  atomic.Int64
+	running    atomic.Int64
+	finished   atomic.Int64
+	failures   atomic.Int64
+	onTaskDone func(err error)
+	observer   TaskObserver
+
+	parent *TaskGroup
+}
+
+// PanicError 任务中的panic被恢复后转换成的错误
+type PanicError struct {
+	Value any
+	Stack []byte
+}
+
+func (e *PanicError) Error() string {
+	return fmt.Sprintf("task panic: %v\n%s", e.Value, e.Stack)
+}
+
+func (e *PanicError) Unwrap() error {
+	if err, ok := e.Value.(error); ok {
+		return err
+	}
+	return nil
+}
+
+// NewTaskGroupWithContext 创建绑定ctx的TaskGroup，任一任务返回错误时取消派生的ctx，
+// 错误仍全部聚合到Wait的返回值中
+func NewTaskGroupWithContext(ctx context.Context) *TaskGroup {
+	ctx, cancel := context.WithCancel(ctx)
+	return &TaskGroup{
+		ctx:    ctx,
+		cancel: cancel,
+	}
+}
+
+// Context 返回传给GoContext任务的ctx。未绑定ctx时惰性创建group自有的根ctx，
+// 在任一任务失败、Wait结束或WaitContext/WaitTimeout放弃等待时取消
+func (ms *TaskGroup) Context() context.Context {
+	ms.mutex.Lock()
+	defer ms.mutex.Unlock()
+	if ms.ctx == nil {
+		ms.ctx, ms.cancel = context.WithCancel(context.Background())
+	}
+	return ms.ctx
+}
+
+func (ms *TaskGroup) cancelContext() {
+	ms.mutex.Lock()
+	defer ms.mutex.Unlock()
+	if ms.cancel != nil {
+		ms.cancel()
+	}
 }
 
+// Go 异步执行f，f中的panic会被恢复并作为*PanicError记录
 func (ms *TaskGroup) Go(f func() error) *TaskGroup {
-	ms.wg.Add(1)
+	ms.submitted.Add(1)
+	for g := ms; g != nil; g = g.parent {
+		g.wg.Add(1)
+	}
 	go func() {
-		ms.done(f())
+		ms.running.Add(1)
+		err := observeCall(ms.observer, f)
+		ms.running.Add(-1)
+		ms.done(err)
 	}()
 	return ms
 }
 
+// Child 创建子group，其ctx派生自当前group的ctx；子group的任务同时计入所有祖先group，
+// 祖先的Wait会等待这些任务结束，错误也会汇总到祖先并触发其ctx取消
+func (ms *TaskGroup) Child() *TaskGroup {
+	child := NewTaskGroupWithContext(ms.Context())
+	child.parent = ms
+	return child
+}
+
+// GoNamed 同Go，返回的错误以"name: "为前缀，便于从聚合错误中定位失败的任务
+func (ms *TaskGroup) GoNamed(name string, f func() error) *TaskGroup {
+	return ms.Go(namedTask(name, f))
+}
+
+func namedTask(name string, f func() error) func() error {
+	return func() error {
+		if err := safeCall(f); err != nil {
+			return fmt.Errorf("%s: %w", name, err)
+		}
+		return nil
+	}
+}
+
+// OnTaskDone 每个任务结束后回调，需在第一次调用Go之前设置；回调可能并发执行
+func (ms *TaskGroup) OnTaskDone(f func(err error)) *TaskGroup {
+	ms.onTaskDone = f
+	return ms
+}
+
+// Observe 设置任务执行指标的观察者，需在第一次调用Go之前设置
+func (ms *TaskGroup) Observe(o TaskObserver) *TaskGroup {
+	ms.observer = o
+	return ms
+}
+
+// Progress 返回已提交、执行中、已结束(含失败)、失败的任务数
+func (ms *TaskGroup) Progress() (submitted, running, done, failed int) {
+	return int(ms.submitted.Load()), int(ms.running.Load()), int(ms.finished.Load()), int(ms.failures.Load())
+}
+
+// RepanicOnWait 所有任务结束后，若有任务panic，在Wait中重新panic第一个PanicError
+func (ms *TaskGroup) RepanicOnWait() *TaskGroup {
+	ms.repanic = true
+	return ms
+}
+
+// GoContext 同Go，f的ctx在兄弟任务失败、父ctx取消或放弃等待时取消，
+// 进行网络I/O的任务应使用ctx以便及时中断
+func (ms *TaskGroup) GoContext(f func(ctx context.Context) error) *TaskGroup {
+	ctx := ms.Context()
+	return ms.Go(func() error {
+		return f(ctx)
+	})
+}
+
+// GoWithTimeout 同GoContext，f的ctx在d后超时；超时即记录ErrTaskTimeout，
+// Wait不再等待该任务，f需自行响应ctx取消以免goroutine泄漏
+func (ms *TaskGroup) GoWithTimeout(d time.Duration, f func(ctx context.Context) error) *TaskGroup {
+	parent := ms.Context()
+	return ms.Go(func() error {
+		ctx, cancel := context.WithTimeout(parent, d)
+		defer cancel()
+
+		errCh := make(chan error, 1)
+		go func() {
+			errCh <- safeCall(func() error { return f(ctx) })
+		}()
+
+		select {
+		case err := <-errCh:
+			return err
+		case <-ctx.Done():
+			select {
+			case err := <-errCh:
+				return err
+			default:
+			}
+			if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+				return fmt.Errorf("%w after %v", ErrTaskTimeout, d)
+			}
+			return ctx.Err()
+		}
+	})
+}
+
+// GoWithRetry 同Go，f失败后最多共执行attempts次，第i次重试前等待backoff*2^(i-1)；
+// 绑定的ctx取消后不再重试，只记录最后一次的错误
+func (ms *TaskGroup) GoWithRetry(f func() error, attempts int, backoff time.Duration) *TaskGroup {
+	ctx := ms.Context()
+	return ms.Go(func() (err error) {
+		delay := backoff
+		for i := 1; ; i++ {
+			if err = safeCall(f); err == nil || i >= attempts {
+				break
+			}
+			select {
+			case <-ctx.Done():
+				return fmt.Errorf("retry aborted after %d attempts: %w", i, err)
+			case <-time.After(delay):
+			}
+			delay *= 2
+		}
+		if err != nil && attempts > 1 {
+			err = fmt.Errorf("failed after %d attempts: %w", attempts, err)
+		}
+		return
+	})
+}
+
 func (ms *TaskGroup) Wait() error {
 	ms.wg.Wait()
+	ms.cancelContext()
+	if ms.repanic && ms.panic != nil {
+		panic(ms.panic)
+	}
 	return ms.err
 }
 
+// WaitFirstError 第一个任务失败时立即返回其错误，不等待其余任务；全部成功时返回nil。
+// 其余任务仍在后台运行，绑定ctx时它们会收到取消信号
+func (ms *TaskGroup) WaitFirstError() error {
+	select {
+	case <-ms.failedCh():
+	case <-ms.waitCh():
+	}
+	ms.cancelContext()
+	ms.mutex.Lock()
+	defer ms.mutex.Unlock()
+	return ms.firstErr
+}
+
+// WaitContext 等待所有任务结束或ctx取消；取消时不再等待剩余任务并取消group的ctx，
+// 返回已收集的错误与ctx.Err()的聚合
+func (ms *TaskGroup) WaitContext(ctx context.Context) error {
+	select {
+	case <-ms.waitCh():
+		return ms.Wait()
+	case <-ctx.Done():
+		ms.cancelContext()
+		ms.mutex.Lock()
+		defer ms.mutex.Unlock()
+		return multierr.Append(ms.err, ctx.Err())
+	}
+}
+
+// WaitTimeout 最多等待d，语义同WaitContext
+func (ms *TaskGroup) WaitTimeout(d time.Duration) error {
+	ctx, cancel := context.WithTimeout(context.Background(), d)
+	defer cancel()
+	return ms.WaitContext(ctx)
+}
+
+// waitCh 所有任务结束后关闭
+func (ms *TaskGroup) waitCh() <-chan struct{} {
+	done := make(chan struct{})
+	go func() {
+		ms.wg.Wait()
+		close(done)
+	}()
+	return done
+}
+
+func (ms *TaskGroup) failedCh() <-chan struct{} {
+	ms.mutex.Lock()
+	defer ms.mutex.Unlock()
+	if ms.failed == nil {
+		ms.failed = make(chan struct{})
+		if ms.firstErr != nil {
+			close(ms.failed)
+		}
+	}
+	return ms.failed
+}
+
 func (ms *TaskGroup) done(err error) {
-	defer ms.wg.Done()
-	if err == nil {
-		return
+	ms.finished.Add(1)
+	if err != nil {
+		ms.failures.Add(1)
 	}
+	if ms.onTaskDone != nil {
+		ms.onTaskDone(err)
+	}
+	for g := ms; g != nil; g = g.parent {
+		if err != nil {
+			g.record(err)
+		}
+		g.wg.Done()
+	}
+}
+
+func (ms *TaskGroup) record(err error) {
 	ms.mutex.Lock()
 	defer ms.mutex.Unlock()
 	ms.err = multierr.Append(ms.err, err)
+	if ms.firstErr == nil {
+		ms.firstErr = err
+		if ms.failed != nil {
+			close(ms.failed)
+		}
+	}
+	var pe *PanicError
+	if ms.panic == nil && errors.As(err, &pe) {
+		ms.panic = pe
+	}
+	if ms.cancel != nil {
+		ms.cancel()
+	}
+}
+
+func safeCall(f func() error) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = &PanicError{Value: r, Stack: debug.Stack()}
+		}
+	}()
+	return f()
 }
 
+// WeightedTaskGroup 信号量容量固定为MAXTASKWEIGHT，其中不可用的部分由reserved占用，
+// Resize通过调整reserved改变实际并发权重
 type WeightedTaskGroup struct {
 	syncer *TaskGroup
 	weight *semaphore.Weighted
+	size   atomic.Int64
+	slots  *semaphore.Weighted // 运行中+排队中的任务数上限，nil表示不限制
+
+	waiting  atomic.Int64 // 等待信号量的任务数
+	observer TaskObserver
+
+	resizeMu   sync.Mutex
+	reserved   atomic.Int64
+	stopShrink func() // 取消进行中的缩容并等待其退出
 }
 
 func NewWeightedTaskGroup(weight int) *WeightedTaskGroup {
-	return &WeightedTaskGroup{
+	weight = Min(Max(weight, 1), MAXTASKWEIGHT)
+	wg := &WeightedTaskGroup{
 		syncer: &TaskGroup{},
-		weight: semaphore.NewWeighted(int64(weight)),
+		weight: semaphore.NewWeighted(MAXTASKWEIGHT),
 	}
+	wg.size.Store(int64(weight))
+	wg.reserved.Store(int64(MAXTASKWEIGHT - weight))
+	wg.weight.TryAcquire(int64(MAXTASKWEIGHT - weight))
+	return wg
+}
+
+// NewBoundedWeightedTaskGroup 最多weight个任务并发执行、maxPending个任务排队，
+// 超出后Go阻塞、TryGo返回ErrQueueFull，避免突发提交创建大量goroutine
+func NewBoundedWeightedTaskGroup(weight, maxPending int) *WeightedTaskGroup {
+	wg := NewWeightedTaskGroup(weight)
+	wg.slots = semaphore.NewWeighted(int64(weight + maxPending))
+	return wg
 }
 
 func (ms *WeightedTaskGroup) Go(f func() error) {
+	ms.GoN(1, f)
+}
+
+// GoN 同Go，f执行期间占用n份权重，n超过总权重时按总权重计算
+func (ms *WeightedTaskGroup) GoN(n int64, f func() error) {
+	if ms.slots != nil {
+		_ = ms.slots.Acquire(context.Background(), 1)
+	}
+	ms.submit(n, f)
+}
+
+// GoContext 同TaskGroup.GoContext
+func (ms *WeightedTaskGroup) GoContext(f func(ctx context.Context) error) {
+	ctx := ms.syncer.Context()
+	ms.Go(func() error {
+		return f(ctx)
+	})
+}
+
+func (ms *WeightedTaskGroup) Context() context.Context {
+	return ms.syncer.Context()
+}
+
+func (ms *WeightedTaskGroup) GoNamed(name string, f func() error) {
+	ms.Go(namedTask(name, f))
+}
+
+// TryGo 排队已满时不阻塞，直接返回ErrQueueFull
+func (ms *WeightedTaskGroup) TryGo(f func() error) error {
+	if ms.slots != nil && !ms.slots.TryAcquire(1) {
+		return ErrQueueFull
+	}
+	ms.submit(1, f)
+	return nil
+}
+
+// Resize 运行时调整并发权重。扩容立即生效；缩容不会中断运行中的任务，
+// 在其结束释放权重后逐步生效。不影响NewBoundedWeightedTaskGroup的排队上限
+func (ms *WeightedTaskGroup) Resize(weight int) {
+	weight = Min(Max(weight, 1), MAXTASKWEIGHT)
+
+	ms.resizeMu.Lock()
+	defer ms.resizeMu.Unlock()
+
+	if ms.stopShrink != nil {
+		ms.stopShrink()
+		ms.stopShrink = nil
+	}
+	ms.size.Store(int64(weight))
+
+	diff := int64(MAXTASKWEIGHT-weight) - ms.reserved.Load()
+	if diff < 0 {
+		ms.reserved.Add(diff)
+		ms.weight.Release(-diff)
+		return
+	}
+	if diff == 0 || ms.weight.TryAcquire(diff) {
+		ms.reserved.Add(diff)
+		return
+	}
+
+	// 权重被运行中的任务占用，后台等待其释放
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		if ms.weight.Acquire(ctx, diff) == nil {
+			ms.reserved.Add(diff)
+		}
+	}()
+	ms.stopShrink = func() {
+		cancel()
+		<-done
+	}
+}
+
+// Size 当前并发权重
+func (ms *WeightedTaskGroup) Size() int {
+	return int(ms.size.Load())
+}
+
+// submit 调用前需已占用slots
+func (ms *WeightedTaskGroup) submit(n int64, f func() error) {
+	n = Min(Max(n, 1), ms.size.Load())
 	ms.syncer.Go(func() error {
-		_ = ms.weight.Acquire(context.Background(), 1)
-		defer ms.weight.Release(1)
+		if ms.slots != nil {
+			defer ms.slots.Release(1)
+		}
+		ms.waiting.Add(1)
+		_ = ms.weight.Acquire(context.Background(), n)
+		ms.waiting.Add(-1)
+		defer ms.weight.Release(n)
 
-		return f()
+		return observeCall(ms.observer, f)
 	})
 }
 
+// Observe 同TaskGroup.Observe，任务耗时不含等待信号量的时间
+func (ms *WeightedTaskGroup) Observe(o TaskObserver) *WeightedTaskGroup {
+	ms.observer = o
+	return ms
+}
+
+func (ms *WeightedTaskGroup) OnTaskDone(f func(err error)) *WeightedTaskGroup {
+	ms.syncer.OnTaskDone(f)
+	return ms
+}
+
+// Progress 同TaskGroup.Progress，running不含等待信号量的任务
+func (ms *WeightedTaskGroup) Progress() (submitted, running, done, failed int) {
+	submitted, running, done, failed = ms.syncer.Progress()
+	running = Max(running-int(ms.waiting.Load()), 0)
+	return
+}
+
+func (ms *WeightedTaskGroup) RepanicOnWait() *WeightedTaskGroup {
+	ms.syncer.RepanicOnWait()
+	return ms
+}
+
 func (ms *WeightedTaskGroup) Wait() error {
 	return ms.syncer.Wait()
 }
+
+func (ms *WeightedTaskGroup) WaitFirstError() error {
+	return ms.syncer.WaitFirstError()
+}
+
+func (ms *WeightedTaskGroup) WaitContext(ctx context.Context) error {
+	return ms.syncer.WaitContext(ctx)
+}
+
+func (ms *WeightedTaskGroup) WaitTimeout(d time.Duration) error {
+	return ms.syncer.WaitTimeout(d)
+}
@@ -0,0 +1,129 @@
+package common
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestTaskGroupRecoversPanic(t *testing.T) {
+	tg := &TaskGroup{}
+	tg.Go(func() error { panic("boom") })
+	tg.Go(func() error { return nil })
+
+	var pe *PanicError
+	if err := tg.Wait(); !errors.As(err, &pe) || pe.Value != "boom" {
+		t.Fatalf("expected PanicError, got %v", err)
+	}
+}
+
+func TestTaskGroupCancelOnError(t *testing.T) {
+	tg := NewTaskGroupWithContext(context.Background())
+	want := errors.New("failed")
+	tg.GoContext(func(ctx context.Context) error { return want })
+	tg.GoContext(func(ctx context.Context) error {
+		<-ctx.Done()
+		return nil
+	})
+	if err := tg.Wait(); !errors.Is(err, want) {
+		t.Fatalf("got %v, want %v", err, want)
+	}
+}
+
+func TestTaskGroupChild(t *testing.T) {
+	parent := NewTaskGroupWithContext(context.Background())
+	child := parent.Child()
+
+	want := errors.New("child failed")
+	child.Go(func() error { return want })
+	parent.GoContext(func(ctx context.Context) error {
+		<-ctx.Done() // cancelled by the child's failure
+		return nil
+	})
+
+	if err := child.Wait(); !errors.Is(err, want) {
+		t.Fatalf("child got %v", err)
+	}
+	if err := parent.Wait(); !errors.Is(err, want) {
+		t.Fatalf("parent got %v", err)
+	}
+}
+
+func TestWeightedTaskGroupResize(t *testing.T) {
+	wg := NewWeightedTaskGroup(1)
+	var (
+		running, peak atomic.Int64
+		release       = make(chan struct{})
+	)
+	task := func() error {
+		n := running.Add(1)
+		for {
+			p := peak.Load()
+			if n <= p || peak.CompareAndSwap(p, n) {
+				break
+			}
+		}
+		<-release
+		running.Add(-1)
+		return nil
+	}
+
+	wg.Resize(3)
+	for i := 0; i < 3; i++ {
+		wg.Go(task)
+	}
+	for running.Load() < 3 {
+		time.Sleep(time.Millisecond)
+	}
+
+	wg.Resize(1)
+	close(release)
+	if err := wg.Wait(); err != nil {
+		t.Fatal(err)
+	}
+	if peak.Load() != 3 {
+		t.Fatalf("peak %d, want 3", peak.Load())
+	}
+
+	// 缩容在运行中的任务结束后生效
+	running.Store(0)
+	peak.Store(0)
+	release = make(chan struct{})
+	for i := 0; i < 3; i++ {
+		wg.Go(task)
+	}
+	time.Sleep(20 * time.Millisecond)
+	close(release)
+	if err := wg.Wait(); err != nil {
+		t.Fatal(err)
+	}
+	if peak.Load() != 1 {
+		t.Fatalf("peak %d after shrink, want 1", peak.Load())
+	}
+}
+
+func TestTaskGroupOwnsContext(t *testing.T) {
+	tg := &TaskGroup{}
+	tg.GoContext(func(ctx context.Context) error {
+		<-ctx.Done()
+		return nil
+	})
+	tg.Go(func() error { return errors.New("failed") })
+	if err := tg.Wait(); err == nil {
+		t.Fatal("expected error")
+	}
+
+	tg = &TaskGroup{}
+	stuck := make(chan struct{})
+	tg.GoContext(func(ctx context.Context) error {
+		<-ctx.Done()
+		close(stuck)
+		return ctx.Err()
+	})
+	if err := tg.WaitTimeout(10 * time.Millisecond); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("got %v", err)
+	}
+	<-stuck
+}
@@ -0,0 +1,106 @@
+package common
+
+import (
+	"slices"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+const (
+	TASKMETRICSSAMPLES = 1024 // 计算分位数保留的最近任务耗时个数, default
+)
+
+// TaskObserver 任务执行指标的观察者，方法可能并发调用
+type TaskObserver interface {
+	TaskStarted()
+	TaskFinished(d time.Duration, err error)
+}
+
+func observeCall(o TaskObserver, f func() error) error {
+	if o == nil {
+		return safeCall(f)
+	}
+	o.TaskStarted()
+	start := time.Now()
+	err := safeCall(f)
+	o.TaskFinished(time.Since(start), err)
+	return err
+}
+
+// TaskMetrics 内置的TaskObserver，统计任务数并按最近samples个任务的耗时计算分位数
+type TaskMetrics struct {
+	started   atomic.Int64
+	succeeded atomic.Int64
+	failed    atomic.Int64
+
+	mu        sync.Mutex
+	durations []time.Duration // 环形缓冲
+	next      int
+}
+
+func NewTaskMetrics(samples int) *TaskMetrics {
+	if samples <= 0 {
+		samples = TASKMETRICSSAMPLES
+	}
+	return &TaskMetrics{durations: make([]time.Duration, 0, samples)}
+}
+
+func (m *TaskMetrics) TaskStarted() {
+	m.started.Add(1)
+}
+
+func (m *TaskMetrics) TaskFinished(d time.Duration, err error) {
+	if err != nil {
+		m.failed.Add(1)
+	} else {
+		m.succeeded.Add(1)
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if len(m.durations) < cap(m.durations) {
+		m.durations = append(m.durations, d)
+		return
+	}
+	m.durations[m.next] = d
+	m.next = (m.next + 1) % len(m.durations)
+}
+
+// TaskMetricsSnapshot TaskMetrics某一时刻的统计
+type TaskMetricsSnapshot struct {
+	Started   int64
+	Succeeded int64
+	Failed    int64
+	P50       time.Duration
+	P99       time.Duration
+}
+
+func (m *TaskMetrics) Snapshot() TaskMetricsSnapshot {
+	m.mu.Lock()
+	sorted := slices.Clone(m.durations)
+	m.mu.Unlock()
+
+	snapshot := TaskMetricsSnapshot{
+		Started:   m.started.Load(),
+		Succeeded: m.succeeded.Load(),
+		Failed:    m.failed.Load(),
+	}
+	if len(sorted) > 0 {
+		slices.Sort(sorted)
+		snapshot.P50 = time.Duration(percentileSorted(sorted, 50))
+		snapshot.P99 = time.Duration(percentileSorted(sorted, 99))
+	}
+	return snapshot
+}
+
+// RegisterGauges 将统计注册到g，指标名以prefix开头，耗时单位为秒
+func (m *TaskMetrics) RegisterGauges(g *Gauges, prefix string) error {
+	return g.RegisterAll(prefix, []GaugeFunc{
+		{"_tasks_started", "tasks started", func() float64 { return float64(m.started.Load()) }},
+		{"_tasks_succeeded", "tasks succeeded", func() float64 { return float64(m.succeeded.Load()) }},
+		{"_tasks_failed", "tasks failed", func() float64 { return float64(m.failed.Load()) }},
+		{"_task_duration_p50_seconds", "median duration of recent tasks", func() float64 { return m.Snapshot().P50.Seconds() }},
+		{"_task_duration_p99_seconds", "p99 duration of recent tasks", func() float64 { return m.Snapshot().P99.Seconds() }},
+	})
+}
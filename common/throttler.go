@@ -0,0 +1,62 @@
+package common
+
+import (
+	"sync"
+	"time"
+)
+
+// Throttler 每个window最多执行一次f：窗口空闲时立即执行，窗口内被抑制的调用不丢弃，
+// 只保留最后一次的f并在窗口结束时执行，保证突发中的最后一次更新不会丢失
+type Throttler struct {
+	mu      sync.Mutex
+	window  time.Duration
+	last    time.Time
+	pending func()
+	timer   *time.Timer
+}
+
+func NewThrottler(window time.Duration) *Throttler {
+	return &Throttler{window: window}
+}
+
+// Call 窗口空闲时在调用方goroutine中立即执行f，否则在窗口结束时于后台执行最后一次传入的f
+func (t *Throttler) Call(f func()) {
+	t.mu.Lock()
+	now := time.Now()
+	if t.timer == nil && now.Sub(t.last) >= t.window {
+		t.last = now
+		t.mu.Unlock()
+		f()
+		return
+	}
+
+	t.pending = f
+	if t.timer == nil {
+		t.timer = time.AfterFunc(t.last.Add(t.window).Sub(now), t.fire)
+	}
+	t.mu.Unlock()
+}
+
+func (t *Throttler) fire() {
+	t.mu.Lock()
+	f := t.pending
+	t.pending, t.timer = nil, nil
+	if f != nil {
+		t.last = time.Now()
+	}
+	t.mu.Unlock()
+	if f != nil {
+		f()
+	}
+}
+
+// Stop 丢弃尚未执行的调用
+func (t *Throttler) Stop() {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.timer != nil {
+		t.timer.Stop()
+		t.timer = nil
+	}
+	t.pending = nil
+}
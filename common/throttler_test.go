@@ -0,0 +1,25 @@
+package common
+
+import (
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestThrottler(t *testing.T) {
+	th := NewThrottler(20 * time.Millisecond)
+	var last, runs atomic.Int64
+	for i := 1; i <= 5; i++ {
+		th.Call(func() {
+			last.Store(int64(i))
+			runs.Add(1)
+		})
+	}
+	if runs.Load() != 1 {
+		t.Fatalf("leading call not executed immediately: %d", runs.Load())
+	}
+	time.Sleep(50 * time.Millisecond)
+	if runs.Load() != 2 || last.Load() != 5 {
+		t.Fatalf("runs %d last %d", runs.Load(), last.Load())
+	}
+}
@@ -0,0 +1,140 @@
+package common
+
+import (
+	"sync"
+	"time"
+
+	"golang.org/x/exp/constraints"
+)
+
+// MergeSum TimeBuckets的求和聚合
+func MergeSum[T constraints.Integer | constraints.Float](a, b T) T {
+	return a + b
+}
+
+// MergeMax TimeBuckets的最大值聚合
+func MergeMax[T constraints.Ordered](a, b T) T {
+	return Max(a, b)
+}
+
+// TimeBucket 一个时间桶的起始时间及聚合值
+type TimeBucket[T any] struct {
+	Start time.Time
+	Value T
+}
+
+// TimeBuckets 在滚动时间范围内维护count个固定宽度的时间桶，写入同一桶的值用merge聚合，
+// 超出范围的桶随时间推进被复用，推进为O(1)(最多清空count个桶)。
+// 用作lag历史、吞吐历史、窗口计数等的底层结构
+type TimeBuckets[T any] struct {
+	mu     sync.Mutex
+	width  time.Duration
+	values []T
+	set    []bool // 对应的桶是否写入过
+	cur    int64  // 最新桶的编号(UnixNano/width)
+	merge  func(a, b T) T
+}
+
+// NewTimeBuckets 覆盖width*count的时间范围，width需大于0
+func NewTimeBuckets[T any](width time.Duration, count int, merge func(a, b T) T) *TimeBuckets[T] {
+	if width <= 0 {
+		panic("non-positive width for NewTimeBuckets")
+	}
+	count = Max(count, 1)
+	return &TimeBuckets[T]{
+		width:  width,
+		values: make([]T, count),
+		set:    make([]bool, count),
+		cur:    time.Now().UnixNano() / int64(width),
+		merge:  merge,
+	}
+}
+
+// Add 将v聚合到当前时间所在的桶
+func (tb *TimeBuckets[T]) Add(v T) {
+	tb.AddAt(time.Now(), v)
+}
+
+// AddAt 将v聚合到t所在的桶，t早于滚动范围时丢弃并返回false
+func (tb *TimeBuckets[T]) AddAt(t time.Time, v T) bool {
+	tb.mu.Lock()
+	defer tb.mu.Unlock()
+
+	id := t.UnixNano() / int64(tb.width)
+	tb.advance(id)
+	if id <= tb.cur-int64(len(tb.values)) {
+		return false
+	}
+	slot := tb.slot(id)
+	if tb.set[slot] {
+		tb.values[slot] = tb.merge(tb.values[slot], v)
+	} else {
+		tb.values[slot], tb.set[slot] = v, true
+	}
+	return true
+}
+
+// Query 聚合与[from, to)有交集的桶，没有写入过的桶时ok为false
+func (tb *TimeBuckets[T]) Query(from, to time.Time) (v T, ok bool) {
+	tb.mu.Lock()
+	defer tb.mu.Unlock()
+
+	tb.advance(time.Now().UnixNano() / int64(tb.width))
+	first := Max(from.UnixNano()/int64(tb.width), tb.cur-int64(len(tb.values))+1)
+	last := Min((to.UnixNano()-1)/int64(tb.width), tb.cur)
+	for id := first; id <= last; id++ {
+		slot := tb.slot(id)
+		if !tb.set[slot] {
+			continue
+		}
+		if ok {
+			v = tb.merge(v, tb.values[slot])
+		} else {
+			v, ok = tb.values[slot], true
+		}
+	}
+	return
+}
+
+// Last 聚合最近d时间内的桶
+func (tb *TimeBuckets[T]) Last(d time.Duration) (T, bool) {
+	now := time.Now()
+	return tb.Query(now.Add(-d), now.Add(tb.width))
+}
+
+// Buckets 按时间升序返回所有写入过的桶
+func (tb *TimeBuckets[T]) Buckets() []TimeBucket[T] {
+	tb.mu.Lock()
+	defer tb.mu.Unlock()
+
+	tb.advance(time.Now().UnixNano() / int64(tb.width))
+	buckets := make([]TimeBucket[T], 0, len(tb.values))
+	for id := tb.cur - int64(len(tb.values)) + 1; id <= tb.cur; id++ {
+		if slot := tb.slot(id); tb.set[slot] {
+			buckets = append(buckets, TimeBucket[T]{
+				Start: time.Unix(0, id*int64(tb.width)),
+				Value: tb.values[slot],
+			})
+		}
+	}
+	return buckets
+}
+
+// advance 推进到编号id的桶，清空被复用的桶
+func (tb *TimeBuckets[T]) advance(id int64) {
+	if id <= tb.cur {
+		return
+	}
+	var zero T
+	from := Max(tb.cur+1, id-int64(len(tb.values))+1)
+	for b := from; b <= id; b++ {
+		slot := tb.slot(b)
+		tb.values[slot], tb.set[slot] = zero, false
+	}
+	tb.cur = id
+}
+
+func (tb *TimeBuckets[T]) slot(id int64) int {
+	n := int64(len(tb.values))
+	return int(((id % n) + n) % n)
+}
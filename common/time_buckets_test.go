@@ -0,0 +1,46 @@
+package common
+
+import (
+	"testing"
+	"time"
+)
+
+func TestTimeBuckets(t *testing.T) {
+	tb := NewTimeBuckets[int](time.Second, 5, MergeSum[int])
+	now := time.Now().Truncate(time.Second)
+
+	tb.AddAt(now, 1)
+	tb.AddAt(now, 2)
+	tb.AddAt(now.Add(-2*time.Second), 10)
+	if tb.AddAt(now.Add(-10*time.Second), 100) {
+		t.Fatal("bucket outside the horizon accepted")
+	}
+
+	if v, ok := tb.Query(now.Add(-5*time.Second), now.Add(time.Second)); !ok || v != 13 {
+		t.Fatalf("query all: %d %v", v, ok)
+	}
+	if v, ok := tb.Query(now, now.Add(time.Second)); !ok || v != 3 {
+		t.Fatalf("query current: %d %v", v, ok)
+	}
+	if _, ok := tb.Query(now.Add(-time.Second), now); ok {
+		t.Fatal("empty range returned a value")
+	}
+	if b := tb.Buckets(); len(b) != 2 || b[0].Value != 10 {
+		t.Fatalf("buckets %+v", b)
+	}
+
+	// 推进超过整个范围后旧桶被清空
+	tb.AddAt(now.Add(6*time.Second), 5)
+	if v, _ := tb.Query(now.Add(-10*time.Second), now.Add(10*time.Second)); v != 5 {
+		t.Fatalf("after advance: %d", v)
+	}
+}
+
+func TestTimeBucketsInvalidWidth(t *testing.T) {
+	defer func() {
+		if recover() == nil {
+			t.Fatal("expected panic for zero width")
+		}
+	}()
+	NewTimeBuckets[int](0, 5, MergeSum[int])
+}
@@ -0,0 +1,132 @@
+package common
+
+import (
+	"context"
+	"errors"
+	"time"
+)
+
+var (
+	ErrExceedsBurst = errors.New("requested tokens exceed bucket burst")
+)
+
+type tokenBucketState struct {
+	tokens float64
+	last   time.Time
+}
+
+// TokenBucket 按key的令牌桶限流，每秒补充rate个令牌，最多积累burst个
+type TokenBucket[K comparable] struct {
+	shards SyncMapGroup[K, tokenBucketState]
+	rate   float64
+	burst  float64
+}
+
+// NewTokenBucket shards需为2的幂，rate需大于0
+func NewTokenBucket[K comparable](shards int, rate float64, burst int) *TokenBucket[K] {
+	if !(rate > 0) {
+		panic("non-positive rate for NewTokenBucket")
+	}
+	return &TokenBucket[K]{
+		shards: NewSyncMapGroup[K, tokenBucketState](shards, 64),
+		rate:   rate,
+		burst:  float64(Max(burst, 1)),
+	}
+}
+
+// refill 补充到now为止的令牌，新key的桶是满的
+func (tb *TokenBucket[K]) refill(st tokenBucketState, loaded bool, now time.Time) tokenBucketState {
+	if !loaded {
+		return tokenBucketState{tokens: tb.burst, last: now}
+	}
+	if elapsed := now.Sub(st.last); elapsed > 0 {
+		st.tokens = Min(tb.burst, st.tokens+elapsed.Seconds()*tb.rate)
+		st.last = now
+	}
+	return st
+}
+
+// Take 同TakeN(key, 1)
+func (tb *TokenBucket[K]) Take(key K) bool {
+	return tb.TakeN(key, 1)
+}
+
+// TakeN 令牌足够时取走n个并返回true，否则不取并返回false
+func (tb *TokenBucket[K]) TakeN(key K, n int) bool {
+	now := time.Now()
+	taken := false
+	tb.shards.ShardFor(key).Compute(key, func(st tokenBucketState, loaded bool) (tokenBucketState, bool) {
+		st = tb.refill(st, loaded, now)
+		if st.tokens >= float64(n) {
+			st.tokens -= float64(n)
+			taken = true
+		}
+		return st, true
+	})
+	return taken
+}
+
+// Wait 同WaitN(ctx, key, 1)
+func (tb *TokenBucket[K]) Wait(ctx context.Context, key K) error {
+	return tb.WaitN(ctx, key, 1)
+}
+
+// WaitN 预占n个令牌并等待其补充到位；ctx先结束时归还预占的令牌并返回ctx.Err()
+func (tb *TokenBucket[K]) WaitN(ctx context.Context, key K, n int) error {
+	if float64(n) > tb.burst {
+		return ErrExceedsBurst
+	}
+	now := time.Now()
+	var wait time.Duration
+	tb.shards.ShardFor(key).Compute(key, func(st tokenBucketState, loaded bool) (tokenBucketState, bool) {
+		st = tb.refill(st, loaded, now)
+		st.tokens -= float64(n)
+		if st.tokens < 0 {
+			wait = time.Duration(-st.tokens / tb.rate * float64(time.Second))
+		}
+		return st, true
+	})
+	if wait <= 0 {
+		return nil
+	}
+
+	timer := time.NewTimer(wait)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		tb.shards.ShardFor(key).Compute(key, func(st tokenBucketState, loaded bool) (tokenBucketState, bool) {
+			st = tb.refill(st, loaded, time.Now())
+			st.tokens = Min(tb.burst, st.tokens+float64(n))
+			return st, true
+		})
+		return ctx.Err()
+	}
+}
+
+// Tokens 返回key当前可用的令牌数，预占未到位时为负数
+func (tb *TokenBucket[K]) Tokens(key K) float64 {
+	st, ok := tb.shards.ShardFor(key).Get(key)
+	return tb.refill(st, ok, time.Now()).tokens
+}
+
+// Sweep 清理令牌已补满的key，可由使用方定期调用
+func (tb *TokenBucket[K]) Sweep() {
+	now := time.Now()
+	for _, shard := range tb.shards {
+		var full []K
+		shard.Range(func(k K, st tokenBucketState) bool {
+			if tb.refill(st, true, now).tokens >= tb.burst {
+				full = append(full, k)
+			}
+			return true
+		})
+		for _, k := range full {
+			shard.Compute(k, func(st tokenBucketState, loaded bool) (tokenBucketState, bool) {
+				st = tb.refill(st, loaded, now)
+				return st, st.tokens < tb.burst
+			})
+		}
+	}
+}
@@ -0,0 +1,52 @@
+package common
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestTokenBucket(t *testing.T) {
+	tb := NewTokenBucket[string](4, 100, 2)
+	if !tb.TakeN("a", 2) || tb.Take("a") {
+		t.Fatal("burst not enforced")
+	}
+	if !tb.Take("b") {
+		t.Fatal("keys must have separate buckets")
+	}
+
+	start := time.Now()
+	if err := tb.Wait(context.Background(), "a"); err != nil {
+		t.Fatal(err)
+	}
+	if waited := time.Since(start); waited < 5*time.Millisecond {
+		t.Fatalf("waited only %v", waited)
+	}
+
+	if err := tb.WaitN(context.Background(), "a", 3); err != ErrExceedsBurst {
+		t.Fatalf("got %v", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	before := tb.Tokens("a")
+	if err := tb.WaitN(ctx, "a", 2); err == nil {
+		t.Fatal("expected ctx error")
+	}
+	if after := tb.Tokens("a"); after < before {
+		t.Fatalf("reservation not returned: %v -> %v", before, after)
+	}
+}
+
+func TestTokenBucketInvalidRate(t *testing.T) {
+	for _, rate := range []float64{0, -1} {
+		func() {
+			defer func() {
+				if recover() == nil {
+					t.Fatalf("rate %v: expected panic", rate)
+				}
+			}()
+			NewTokenBucket[string](4, rate, 1)
+		}()
+	}
+}
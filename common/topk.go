@@ -0,0 +1,110 @@
+package common
+
+import (
+	"container/heap"
+	"sort"
+	"sync"
+)
+
+type topKEntry[K comparable, T any] struct {
+	key   K
+	value T
+	score float64
+}
+
+// topKHeap 按score的小顶堆，同时维护key到下标的索引
+type topKHeap[K comparable, T any] struct {
+	entries []topKEntry[K, T]
+	index   map[K]int
+}
+
+func (h *topKHeap[K, T]) Len() int           { return len(h.entries) }
+func (h *topKHeap[K, T]) Less(i, j int) bool { return h.entries[i].score < h.entries[j].score }
+func (h *topKHeap[K, T]) Swap(i, j int) {
+	h.entries[i], h.entries[j] = h.entries[j], h.entries[i]
+	h.index[h.entries[i].key] = i
+	h.index[h.entries[j].key] = j
+}
+func (h *topKHeap[K, T]) Push(x any) {
+	e := x.(topKEntry[K, T])
+	h.index[e.key] = len(h.entries)
+	h.entries = append(h.entries, e)
+}
+func (h *topKHeap[K, T]) Pop() any {
+	n := len(h.entries)
+	e := h.entries[n-1]
+	h.entries = h.entries[:n-1]
+	delete(h.index, e.key)
+	return e
+}
+
+// TopK 并发安全地维护score最高的k个元素，同一key只保留最新值。
+// 已在榜内的元素分数下降后仍会保留，直到被更高分的元素挤出
+type TopK[K comparable, T any] struct {
+	mu    sync.Mutex
+	k     int
+	key   func(T) K
+	score func(T) float64
+	h     *topKHeap[K, T]
+}
+
+// NewTopK k<=0时不保留任何元素
+func NewTopK[K comparable, T any](k int, key func(T) K, score func(T) float64) *TopK[K, T] {
+	k = Max(k, 0)
+	return &TopK[K, T]{
+		k:     k,
+		key:   key,
+		score: score,
+		h: &topKHeap[K, T]{
+			entries: make([]topKEntry[K, T], 0, k),
+			index:   make(map[K]int, k),
+		},
+	}
+}
+
+// Offer 提交元素的最新值，返回其是否在榜内
+func (t *TopK[K, T]) Offer(v T) bool {
+	e := topKEntry[K, T]{key: t.key(v), value: v, score: t.score(v)}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if i, ok := t.h.index[e.key]; ok {
+		t.h.entries[i] = e
+		heap.Fix(t.h, i)
+		return true
+	}
+	if t.h.Len() < t.k {
+		heap.Push(t.h, e)
+		return true
+	}
+	if t.k == 0 || e.score <= t.h.entries[0].score {
+		return false
+	}
+	delete(t.h.index, t.h.entries[0].key)
+	t.h.entries[0] = e
+	t.h.index[e.key] = 0
+	heap.Fix(t.h, 0)
+	return true
+}
+
+func (t *TopK[K, T]) Remove(key K) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if i, ok := t.h.index[key]; ok {
+		heap.Remove(t.h, i)
+	}
+}
+
+// Snapshot 按score从高到低返回榜内元素
+func (t *TopK[K, T]) Snapshot() []T {
+	t.mu.Lock()
+	entries := append([]topKEntry[K, T](nil), t.h.entries...)
+	t.mu.Unlock()
+
+	sort.Slice(entries, func(i, j int) bool { return entries[i].score > entries[j].score })
+	r := make([]T, len(entries))
+	for i, e := range entries {
+		r[i] = e.value
+	}
+	return r
+}
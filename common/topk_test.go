@@ -0,0 +1,32 @@
+package common
+
+import (
+	"testing"
+)
+
+func TestTopK(t *testing.T) {
+	type rate struct {
+		symbol string
+		n      float64
+	}
+	tk := NewTopK(2, func(r rate) string { return r.symbol }, func(r rate) float64 { return r.n })
+	tk.Offer(rate{"a", 1})
+	tk.Offer(rate{"b", 5})
+	tk.Offer(rate{"c", 3})
+	tk.Offer(rate{"a", 2}) // below the current minimum, rejected
+	tk.Offer(rate{"c", 9})
+
+	got := tk.Snapshot()
+	if len(got) != 2 || got[0].symbol != "c" || got[1].symbol != "b" {
+		t.Fatalf("unexpected snapshot %v", got)
+	}
+}
+
+func TestTopKNonPositive(t *testing.T) {
+	for _, k := range []int{0, -1} {
+		tk := NewTopK(k, func(v int) int { return v }, func(v int) float64 { return float64(v) })
+		if tk.Offer(1) || len(tk.Snapshot()) != 0 {
+			t.Fatalf("k=%d: element kept", k)
+		}
+	}
+}
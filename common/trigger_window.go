@@ -5,43 +5,244 @@ import (
 	"time"
 )
 
-type TriggerWindow[T comparable] struct {
-	mu       *sync.Mutex
-	records  map[T][]time.Time
-	interval time.Duration
+const (
+	TRIGGERIDLEINTERVALS = 10 // symbol空闲超过该倍数的interval后由janitor清理, default
+)
+
+// timeRing 固定容量的时间戳环形缓冲，按写入顺序(即时间顺序)保存
+type timeRing struct {
+	times []time.Time
+	head  int // 最早时间戳的下标
+	n     int
+	last  time.Time // 最后一次写入时间，reset后仍保留
+}
+
+func newTimeRing(capacity int) *timeRing {
+	return &timeRing{times: make([]time.Time, Max(capacity, 1))}
+}
+
+// evict 丢弃与now间隔超过interval的时间戳
+func (r *timeRing) evict(now time.Time, interval time.Duration) {
+	for r.n > 0 && now.Sub(r.times[r.head]) > interval {
+		r.head = (r.head + 1) % len(r.times)
+		r.n--
+	}
+}
+
+// push 缓冲已满时覆盖最早的时间戳
+func (r *timeRing) push(t time.Time) {
+	if r.n == len(r.times) {
+		r.head = (r.head + 1) % len(r.times)
+		r.n--
+	}
+	r.times[(r.head+r.n)%len(r.times)] = t
+	r.n++
+	r.last = t
+}
+
+// count 返回与now间隔不超过interval的时间戳个数，不修改缓冲
+func (r *timeRing) count(now time.Time, interval time.Duration) int {
+	for i := 0; i < r.n; i++ {
+		if now.Sub(r.times[(r.head+i)%len(r.times)]) <= interval {
+			return r.n - i
+		}
+	}
+	return 0
+}
+
+// resize 调整容量，只保留最新的时间戳
+func (r *timeRing) resize(capacity int) {
+	capacity = Max(capacity, 1)
+	if capacity == len(r.times) {
+		return
+	}
+	keep := Min(r.n, capacity)
+	times := make([]time.Time, capacity)
+	for i := 0; i < keep; i++ {
+		times[i] = r.times[(r.head+r.n-keep+i)%len(r.times)]
+	}
+	r.times, r.head, r.n = times, 0, keep
+}
+
+func (r *timeRing) reset() {
+	r.head, r.n = 0, 0
+}
+
+type TriggerWindowOption[T comparable] func(*TriggerWindow[T])
+
+// WithOnReached 达到limit时回调，count为达到时窗口内的触发次数；回调在Trigger的调用方goroutine中执行
+func WithOnReached[T comparable](fn func(symbol T, count int)) TriggerWindowOption[T] {
+	return func(tc *TriggerWindow[T]) {
+		tc.onReached = fn
+	}
+}
+
+// triggerLimits 单个symbol覆盖的阈值
+type triggerLimits struct {
 	limit    int
+	interval time.Duration
+}
+
+// WithClock 使用指定的时间来源，默认SystemClock
+func WithClock[T comparable](clock Clock) TriggerWindowOption[T] {
+	return func(tc *TriggerWindow[T]) {
+		if clock != nil {
+			tc.clock = clock
+		}
+	}
+}
+
+type TriggerWindow[T comparable] struct {
+	mu        *sync.Mutex
+	records   map[T]*timeRing
+	interval  time.Duration
+	limit     int
+	overrides map[T]triggerLimits
+	onReached func(symbol T, count int)
+	clock     Clock
 }
 
 func (tc *TriggerWindow[T]) Trigger(symbol T) (reached bool) {
+	var count int
+	reached, count = tc.trigger(symbol)
+	if reached && tc.onReached != nil {
+		tc.onReached(symbol, count)
+	}
+	return
+}
+
+func (tc *TriggerWindow[T]) trigger(symbol T) (reached bool, count int) {
 	tc.mu.Lock()
 	defer tc.mu.Unlock()
 
-	currentTime := time.Now()
-	if _, exists := tc.records[symbol]; !exists {
-		tc.records[symbol] = make([]time.Time, 0, 32)
+	currentTime := tc.clock.Now()
+	limit, interval := tc.limits(symbol)
+	ring, exists := tc.records[symbol]
+	if !exists {
+		ring = newTimeRing(limit)
+		tc.records[symbol] = ring
 	}
 
-	var validTimes []time.Time
-	for _, t := range tc.records[symbol] {
-		if currentTime.Sub(t) <= tc.interval {
-			validTimes = append(validTimes, t)
-		}
-	}
-	tc.records[symbol] = validTimes
-	tc.records[symbol] = append(tc.records[symbol], currentTime)
+	ring.evict(currentTime, interval)
+	ring.push(currentTime)
 
-	reached = len(tc.records[symbol]) >= tc.limit
+	count = ring.n
+	reached = count >= limit
 	if reached { // 达到次数后清空
-		tc.records[symbol] = make([]time.Time, 0, 32)
+		ring.reset()
 	}
 	return
 }
 
-func NewTriggerWindow[T comparable](limit int, interval time.Duration) *TriggerWindow[T] {
-	return &TriggerWindow[T]{
-		mu:       &sync.Mutex{},
-		limit:    limit,
-		interval: interval,
-		records:  make(map[T][]time.Time, 128),
+// Count 返回symbol当前窗口内的触发次数，不记录本次查询
+func (tc *TriggerWindow[T]) Count(symbol T) int {
+	tc.mu.Lock()
+	defer tc.mu.Unlock()
+	ring, exists := tc.records[symbol]
+	if !exists {
+		return 0
+	}
+	_, interval := tc.limits(symbol)
+	return ring.count(tc.clock.Now(), interval)
+}
+
+// Remaining 返回symbol距达到limit还需的触发次数
+func (tc *TriggerWindow[T]) Remaining(symbol T) int {
+	count := tc.Count(symbol)
+	tc.mu.Lock()
+	limit, _ := tc.limits(symbol)
+	tc.mu.Unlock()
+	return Max(limit-count, 0)
+}
+
+// SetLimits 为symbol单独设置limit和interval，覆盖默认值；已记录的触发保留
+func (tc *TriggerWindow[T]) SetLimits(symbol T, limit int, interval time.Duration) {
+	tc.mu.Lock()
+	defer tc.mu.Unlock()
+	tc.overrides[symbol] = triggerLimits{limit: limit, interval: interval}
+	if ring, exists := tc.records[symbol]; exists {
+		ring.resize(limit)
+	}
+}
+
+// ClearLimits 移除symbol的单独设置，恢复默认值
+func (tc *TriggerWindow[T]) ClearLimits(symbol T) {
+	tc.mu.Lock()
+	defer tc.mu.Unlock()
+	delete(tc.overrides, symbol)
+	if ring, exists := tc.records[symbol]; exists {
+		ring.resize(tc.limit)
+	}
+}
+
+// limits 调用前需持有锁
+func (tc *TriggerWindow[T]) limits(symbol T) (int, time.Duration) {
+	if l, ok := tc.overrides[symbol]; ok {
+		return l.limit, l.interval
+	}
+	return tc.limit, tc.interval
+}
+
+func NewTriggerWindow[T comparable](limit int, interval time.Duration, opts ...TriggerWindowOption[T]) *TriggerWindow[T] {
+	tc := &TriggerWindow[T]{
+		mu:        &sync.Mutex{},
+		limit:     limit,
+		interval:  interval,
+		records:   make(map[T]*timeRing, 128),
+		overrides: make(map[T]triggerLimits),
+		clock:     SystemClock,
+	}
+	for _, opt := range opts {
+		opt(tc)
+	}
+	return tc
+}
+
+// Sweep 清理最后一次触发距今超过idle的symbol，返回清理的数量
+func (tc *TriggerWindow[T]) Sweep(idle time.Duration) int {
+	tc.mu.Lock()
+	defer tc.mu.Unlock()
+
+	now := tc.clock.Now()
+	removed := 0
+	for symbol, ring := range tc.records {
+		if now.Sub(ring.last) > idle {
+			delete(tc.records, symbol)
+			removed++
+		}
+	}
+	return removed
+}
+
+// StartJanitor 后台定期清理空闲超过idleIntervals倍interval的symbol，
+// idleIntervals<=0时使用TRIGGERIDLEINTERVALS；返回的stop停止清理
+func (tc *TriggerWindow[T]) StartJanitor(idleIntervals int) (stop func()) {
+	return startTriggerJanitor(tc.interval, idleIntervals, func(idle time.Duration) { tc.Sweep(idle) })
+}
+
+func startTriggerJanitor(interval time.Duration, idleIntervals int, sweep func(idle time.Duration)) (stop func()) {
+	if idleIntervals <= 0 {
+		idleIntervals = TRIGGERIDLEINTERVALS
+	}
+	idle := interval * time.Duration(idleIntervals)
+
+	var (
+		stopCh = make(chan struct{})
+		once   sync.Once
+	)
+	go func() {
+		ticker := time.NewTicker(idle)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-stopCh:
+				return
+			case <-ticker.C:
+				sweep(idle)
+			}
+		}
+	}()
+	return func() {
+		once.Do(func() { close(stopCh) })
 	}
 }
@@ -0,0 +1,105 @@
+package common
+
+import (
+	"io"
+	"time"
+)
+
+// ShardedTriggerWindow 按symbol哈希分片的TriggerWindow，各分片独立加锁，
+// 适用于大量symbol高并发触发的场景，API与TriggerWindow一致
+type ShardedTriggerWindow[T comparable] []*TriggerWindow[T]
+
+// NewShardedTriggerWindow shards需为2的幂
+func NewShardedTriggerWindow[T comparable](shards, limit int, interval time.Duration, opts ...TriggerWindowOption[T]) ShardedTriggerWindow[T] {
+	if !IsPowerOfTwo(shards) {
+		panic("not power of two")
+	}
+	st := make(ShardedTriggerWindow[T], shards)
+	for i := range st {
+		st[i] = NewTriggerWindow(limit, interval, opts...)
+	}
+	return st
+}
+
+// ShardFor 返回symbol所属的分片
+func (st ShardedTriggerWindow[T]) ShardFor(symbol T) *TriggerWindow[T] {
+	return st[HashKey(symbol)&uint64(len(st)-1)]
+}
+
+func (st ShardedTriggerWindow[T]) Trigger(symbol T) bool {
+	return st.ShardFor(symbol).Trigger(symbol)
+}
+
+func (st ShardedTriggerWindow[T]) Count(symbol T) int {
+	return st.ShardFor(symbol).Count(symbol)
+}
+
+func (st ShardedTriggerWindow[T]) Remaining(symbol T) int {
+	return st.ShardFor(symbol).Remaining(symbol)
+}
+
+func (st ShardedTriggerWindow[T]) SetLimits(symbol T, limit int, interval time.Duration) {
+	st.ShardFor(symbol).SetLimits(symbol, limit, interval)
+}
+
+func (st ShardedTriggerWindow[T]) ClearLimits(symbol T) {
+	st.ShardFor(symbol).ClearLimits(symbol)
+}
+
+func (st ShardedTriggerWindow[T]) Sweep(idle time.Duration) int {
+	removed := 0
+	for _, tw := range st {
+		removed += tw.Sweep(idle)
+	}
+	return removed
+}
+
+// StartJanitor 同TriggerWindow.StartJanitor，所有分片共用一个后台goroutine
+func (st ShardedTriggerWindow[T]) StartJanitor(idleIntervals int) (stop func()) {
+	return startTriggerJanitor(st[0].interval, idleIntervals, func(idle time.Duration) { st.Sweep(idle) })
+}
+
+func (st ShardedTriggerWindow[T]) Export() map[T][]time.Time {
+	state := make(map[T][]time.Time)
+	for _, tw := range st {
+		for symbol, times := range tw.Export() {
+			state[symbol] = times
+		}
+	}
+	return state
+}
+
+func (st ShardedTriggerWindow[T]) Import(state map[T][]time.Time) {
+	parts := make([]map[T][]time.Time, len(st))
+	for symbol, times := range state {
+		i := HashKey(symbol) & uint64(len(st)-1)
+		if parts[i] == nil {
+			parts[i] = make(map[T][]time.Time)
+		}
+		parts[i][symbol] = times
+	}
+	for i, part := range parts {
+		if part != nil {
+			st[i].Import(part)
+		}
+	}
+}
+
+func (st ShardedTriggerWindow[T]) SaveTo(w io.Writer, codec Codec) error {
+	if codec == nil {
+		codec = GobCodec
+	}
+	return codec.Encode(w, st.Export())
+}
+
+func (st ShardedTriggerWindow[T]) LoadFrom(r io.Reader, codec Codec) error {
+	if codec == nil {
+		codec = GobCodec
+	}
+	state := make(map[T][]time.Time)
+	if err := codec.Decode(r, &state); err != nil {
+		return err
+	}
+	st.Import(state)
+	return nil
+}
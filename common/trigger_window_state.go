@@ -0,0 +1,74 @@
+package common
+
+import (
+	"io"
+	"slices"
+	"time"
+)
+
+// slice 按时间升序返回缓冲中的时间戳
+func (r *timeRing) slice() []time.Time {
+	times := make([]time.Time, r.n)
+	for i := range times {
+		times[i] = r.times[(r.head+i)%len(r.times)]
+	}
+	return times
+}
+
+// Export 导出各symbol窗口内未过期的触发时间(升序)，用于滚动重启时保留累计的计数
+func (tc *TriggerWindow[T]) Export() map[T][]time.Time {
+	tc.mu.Lock()
+	defer tc.mu.Unlock()
+
+	now := tc.clock.Now()
+	state := make(map[T][]time.Time, len(tc.records))
+	for symbol, ring := range tc.records {
+		_, interval := tc.limits(symbol)
+		ring.evict(now, interval)
+		if ring.n > 0 {
+			state[symbol] = ring.slice()
+		}
+	}
+	return state
+}
+
+// Import 用state替换对应symbol的窗口状态，已过期的时间戳被丢弃，超过limit时只保留最新的
+func (tc *TriggerWindow[T]) Import(state map[T][]time.Time) {
+	tc.mu.Lock()
+	defer tc.mu.Unlock()
+
+	now := tc.clock.Now()
+	for symbol, times := range state {
+		limit, interval := tc.limits(symbol)
+		times = slices.Clone(times)
+		slices.SortFunc(times, func(a, b time.Time) int { return a.Compare(b) })
+
+		ring := newTimeRing(limit)
+		for _, t := range times {
+			ring.push(t)
+		}
+		ring.evict(now, interval)
+		tc.records[symbol] = ring
+	}
+}
+
+// SaveTo 将Export的结果写入w，codec为nil时使用GobCodec
+func (tc *TriggerWindow[T]) SaveTo(w io.Writer, codec Codec) error {
+	if codec == nil {
+		codec = GobCodec
+	}
+	return codec.Encode(w, tc.Export())
+}
+
+// LoadFrom 从r读取SaveTo写入的状态并Import，codec为nil时使用GobCodec
+func (tc *TriggerWindow[T]) LoadFrom(r io.Reader, codec Codec) error {
+	if codec == nil {
+		codec = GobCodec
+	}
+	state := make(map[T][]time.Time)
+	if err := codec.Decode(r, &state); err != nil {
+		return err
+	}
+	tc.Import(state)
+	return nil
+}
@@ -0,0 +1,108 @@
+package common
+
+import (
+	"bytes"
+	"testing"
+	"time"
+)
+
+func TestTriggerWindow(t *testing.T) {
+	clock := NewManualClock(time.Now())
+	tw := NewTriggerWindow(3, 50*time.Millisecond, WithClock[string](clock))
+	for i := 0; i < 2; i++ {
+		if tw.Trigger("a") {
+			t.Fatalf("reached after %d triggers", i+1)
+		}
+	}
+	if n, left := tw.Count("a"), tw.Remaining("a"); n != 2 || left != 1 {
+		t.Fatalf("count %d remaining %d", n, left)
+	}
+	if tw.Trigger("b") {
+		t.Fatal("symbols must be counted separately")
+	}
+	if !tw.Trigger("a") {
+		t.Fatal("expected reached on 3rd trigger")
+	}
+	if tw.Trigger("a") {
+		t.Fatal("window must reset after reaching the limit")
+	}
+
+	// 超出interval的触发不再计数
+	tw.Trigger("c")
+	tw.Trigger("c")
+	clock.Advance(60 * time.Millisecond)
+	if tw.Trigger("c") {
+		t.Fatal("expired triggers were counted")
+	}
+}
+
+func BenchmarkTriggerWindow(b *testing.B) {
+	tw := NewTriggerWindow[int](100, time.Second)
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		tw.Trigger(i & 1023)
+	}
+}
+
+func TestTriggerWindowSetLimits(t *testing.T) {
+	tw := NewTriggerWindow[string](3, time.Second)
+	tw.Trigger("a")
+	tw.SetLimits("a", 2, time.Second)
+	if !tw.Trigger("a") {
+		t.Fatal("override limit not applied")
+	}
+	if tw.Remaining("b") != 3 {
+		t.Fatal("default limit changed")
+	}
+	tw.ClearLimits("a")
+	if tw.Remaining("a") != 3 {
+		t.Fatal("override not cleared")
+	}
+}
+
+func TestTriggerWindowSaveLoad(t *testing.T) {
+	tw := NewTriggerWindow[string](3, time.Minute)
+	tw.Trigger("a")
+	tw.Trigger("a")
+
+	var buf bytes.Buffer
+	if err := tw.SaveTo(&buf, nil); err != nil {
+		t.Fatal(err)
+	}
+	restored := NewTriggerWindow[string](3, time.Minute)
+	if err := restored.LoadFrom(&buf, nil); err != nil {
+		t.Fatal(err)
+	}
+	if !restored.Trigger("a") {
+		t.Fatal("restored window lost its count")
+	}
+}
+
+func TestSlidingLimiter(t *testing.T) {
+	sl := NewSlidingLimiter[string](2, 50*time.Millisecond)
+	if !sl.Allow("a") || !sl.Allow("a") {
+		t.Fatal("first two calls must be allowed")
+	}
+	if sl.Allow("a") || sl.Remaining("a") != 0 {
+		t.Fatal("third call must be rejected")
+	}
+	if !sl.Allow("b") {
+		t.Fatal("keys must be limited separately")
+	}
+	time.Sleep(60 * time.Millisecond)
+	if !sl.Allow("a") {
+		t.Fatal("window did not slide")
+	}
+}
+
+func BenchmarkShardedTriggerWindow(b *testing.B) {
+	tw := NewShardedTriggerWindow[int](16, 100, time.Second)
+	b.ReportAllocs()
+	b.RunParallel(func(pb *testing.PB) {
+		i := 0
+		for pb.Next() {
+			tw.Trigger(i & 1023)
+			i++
+		}
+	})
+}
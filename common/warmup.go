@@ -0,0 +1,112 @@
+package common
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"sync/atomic"
+	"time"
+
+	"go.uber.org/multierr"
+)
+
+const (
+	WARMUPPROGRESSEVERY = 1000
+)
+
+var (
+	ErrWarmUpTooManyErrors = errors.New("warm up aborted: too many errors")
+)
+
+// WarmUpIterator 预热数据源(DB游标、compacted topic等)，Next返回io.EOF表示结束，
+// 其他错误视为单条数据失败；Next只会被单个goroutine调用
+type WarmUpIterator[K comparable, V any] interface {
+	Next(ctx context.Context) (K, V, error)
+}
+
+// WarmUpTarget 被预热的缓存，SyncMap、SyncMapGroup均满足
+type WarmUpTarget[K comparable, V any] interface {
+	Update(K, V)
+}
+
+type WarmUpOptions struct {
+	MaxErrors     int // 允许失败的条数，超过后中止预热
+	ProgressEvery int // 每处理多少条回调一次OnProgress, default WARMUPPROGRESSEVERY
+	OnProgress    func(WarmUpProgress)
+}
+
+type WarmUpProgress struct {
+	Loaded  int
+	Failed  int
+	Elapsed time.Duration
+}
+
+type warmUpEntry[K comparable, V any] struct {
+	k K
+	v V
+}
+
+// WarmUp 单goroutine读取it，由concurrency个goroutine写入target，结束时回调一次最终进度
+func WarmUp[K comparable, V any](ctx context.Context, target WarmUpTarget[K, V], it WarmUpIterator[K, V], concurrency int, opts WarmUpOptions) (WarmUpProgress, error) {
+	if opts.ProgressEvery <= 0 {
+		opts.ProgressEvery = WARMUPPROGRESSEVERY
+	}
+	concurrency = Max(concurrency, 1)
+
+	var (
+		start          = time.Now()
+		loaded, failed atomic.Int64
+		errs           error
+		entries        = make(chan warmUpEntry[K, V], concurrency*2)
+		tg             TaskGroup
+	)
+	progress := func() WarmUpProgress {
+		return WarmUpProgress{
+			Loaded:  int(loaded.Load()),
+			Failed:  int(failed.Load()),
+			Elapsed: time.Since(start),
+		}
+	}
+
+	for i := 0; i < concurrency; i++ {
+		tg.Go(func() error {
+			for e := range entries {
+				target.Update(e.k, e.v)
+				if n := loaded.Add(1); opts.OnProgress != nil && n%int64(opts.ProgressEvery) == 0 {
+					opts.OnProgress(progress())
+				}
+			}
+			return nil
+		})
+	}
+
+	for {
+		if err := ctx.Err(); err != nil {
+			errs = multierr.Append(errs, err)
+			break
+		}
+		k, v, err := it.Next(ctx)
+		if err == io.EOF {
+			break
+		} else if err != nil {
+			if n := failed.Add(1); int(n) > opts.MaxErrors {
+				errs = multierr.Append(errs, fmt.Errorf("%w: %v", ErrWarmUpTooManyErrors, err))
+				break
+			}
+			continue
+		}
+		select {
+		case entries <- warmUpEntry[K, V]{k: k, v: v}:
+		case <-ctx.Done():
+		}
+	}
+	close(entries)
+	errs = multierr.Append(errs, tg.Wait())
+
+	p := progress()
+	if opts.OnProgress != nil {
+		opts.OnProgress(p)
+	}
+	return p, errs
+}
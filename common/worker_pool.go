@@ -0,0 +1,107 @@
+package common
+
+import (
+	"context"
+	"errors"
+	"log"
+	"sync"
+)
+
+var (
+	ErrPoolStopped = errors.New("worker pool stopped")
+)
+
+// WorkerPool 常驻的固定数量worker，与一次性的TaskGroup不同，可长期复用
+type WorkerPool struct {
+	tasks   chan func()
+	wg      sync.WaitGroup
+	mu      sync.RWMutex
+	stopped bool
+	onPanic func(*PanicError)
+}
+
+// NewWorkerPool 启动workers个worker，最多queueSize个任务排队
+func NewWorkerPool(workers, queueSize int) *WorkerPool {
+	p := &WorkerPool{
+		tasks: make(chan func(), queueSize),
+	}
+	p.wg.Add(workers)
+	for i := 0; i < workers; i++ {
+		go p.work()
+	}
+	return p
+}
+
+// OnPanic 任务panic时的回调，默认使用log输出，需在Submit之前设置
+func (p *WorkerPool) OnPanic(f func(*PanicError)) *WorkerPool {
+	p.onPanic = f
+	return p
+}
+
+// Submit 提交任务，队列满时阻塞
+func (p *WorkerPool) Submit(f func()) error {
+	return p.SubmitContext(context.Background(), f)
+}
+
+// SubmitContext 提交任务，队列满时阻塞直到ctx取消
+func (p *WorkerPool) SubmitContext(ctx context.Context, f func()) error {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	if p.stopped {
+		return ErrPoolStopped
+	}
+	select {
+	case p.tasks <- f:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// TrySubmit 队列满时不阻塞，返回ErrQueueFull
+func (p *WorkerPool) TrySubmit(f func()) error {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	if p.stopped {
+		return ErrPoolStopped
+	}
+	select {
+	case p.tasks <- f:
+		return nil
+	default:
+		return ErrQueueFull
+	}
+}
+
+// Pending 排队中的任务数
+func (p *WorkerPool) Pending() int {
+	return len(p.tasks)
+}
+
+// Stop 不再接受新任务，等待已排队的任务执行完毕后返回
+func (p *WorkerPool) Stop() {
+	p.mu.Lock()
+	if !p.stopped {
+		p.stopped = true
+		close(p.tasks)
+	}
+	p.mu.Unlock()
+	p.wg.Wait()
+}
+
+func (p *WorkerPool) work() {
+	defer p.wg.Done()
+	for f := range p.tasks {
+		err := safeCall(func() error {
+			f()
+			return nil
+		})
+		if pe, ok := err.(*PanicError); ok {
+			if p.onPanic != nil {
+				p.onPanic(pe)
+			} else {
+				log.Printf("worker pool: %v", pe)
+			}
+		}
+	}
+}
@@ -0,0 +1,39 @@
+package jsonize
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+)
+
+var (
+	ErrNotArray = errors.New("json value is not an array")
+)
+
+// DecodeArrayStream 逐个解码json数组中的元素并回调fn，不会将整个数组读入内存.
+// fn返回错误时立即停止并返回该错误.
+func DecodeArrayStream[T any](r io.Reader, fn func(T) error) error {
+	dec := json.NewDecoder(r)
+
+	tok, err := dec.Token()
+	if err != nil {
+		return err
+	}
+	if d, ok := tok.(json.Delim); !ok || d != '[' {
+		return fmt.Errorf("%w: got %v", ErrNotArray, tok)
+	}
+
+	for i := 0; dec.More(); i++ {
+		var v T
+		if err := dec.Decode(&v); err != nil {
+			return fmt.Errorf("decode element %d: %w", i, err)
+		}
+		if err := fn(v); err != nil {
+			return err
+		}
+	}
+
+	_, err = dec.Token() // 消费结尾的']'
+	return err
+}
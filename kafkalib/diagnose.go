@@ -0,0 +1,156 @@
+package kafkalib
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"net"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/segmentio/kafka-go"
+)
+
+const (
+	DIAGNOSETIMEOUT = 5 * time.Second // 每个步骤的超时, default
+)
+
+const (
+	StepDNS         = "dns"
+	StepDial        = "dial"
+	StepTLS         = "tls"
+	StepAPIVersions = "api_versions"
+)
+
+// DiagnoseStep 单个检查步骤的结果
+type DiagnoseStep struct {
+	Name     string
+	Duration time.Duration
+	Err      error
+}
+
+// BrokerReport 单个broker的连通性报告，步骤按执行顺序排列，遇到失败即停止
+type BrokerReport struct {
+	Broker      string
+	Addrs       []string // DNS解析结果
+	Steps       []DiagnoseStep
+	APIVersions []kafka.ApiVersion
+}
+
+// OK 所有步骤都成功
+func (r *BrokerReport) OK() bool {
+	for _, s := range r.Steps {
+		if s.Err != nil {
+			return false
+		}
+	}
+	return len(r.Steps) > 0
+}
+
+// FailedStep 返回第一个失败的步骤，全部成功时返回nil
+func (r *BrokerReport) FailedStep() *DiagnoseStep {
+	for i := range r.Steps {
+		if r.Steps[i].Err != nil {
+			return &r.Steps[i]
+		}
+	}
+	return nil
+}
+
+func (r *BrokerReport) String() string {
+	var b strings.Builder
+	b.WriteString(r.Broker)
+	for _, s := range r.Steps {
+		if s.Err != nil {
+			fmt.Fprintf(&b, " %s=FAIL(%s, %v)", s.Name, s.Duration, s.Err)
+		} else {
+			fmt.Fprintf(&b, " %s=ok(%s)", s.Name, s.Duration)
+		}
+	}
+	return b.String()
+}
+
+// Diagnose 并发检查每个broker的DNS解析、TCP连接、TLS握手和API版本协商，
+// 用于排查网络还是broker本身的问题。dialer为nil时使用kafka.DefaultDialer
+func Diagnose(ctx context.Context, brokers []string, dialer *kafka.Dialer) []*BrokerReport {
+	if dialer == nil {
+		dialer = kafka.DefaultDialer
+	}
+	reports := make([]*BrokerReport, len(brokers))
+	var wg sync.WaitGroup
+	for i, broker := range brokers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			reports[i] = diagnoseBroker(ctx, broker, dialer)
+		}()
+	}
+	wg.Wait()
+	return reports
+}
+
+func diagnoseBroker(ctx context.Context, broker string, dialer *kafka.Dialer) *BrokerReport {
+	report := &BrokerReport{Broker: broker}
+	step := func(name string, f func(ctx context.Context) error) bool {
+		ctx, cancel := context.WithTimeout(ctx, DIAGNOSETIMEOUT)
+		defer cancel()
+		start := time.Now()
+		err := f(ctx)
+		report.Steps = append(report.Steps, DiagnoseStep{Name: name, Duration: time.Since(start), Err: err})
+		return err == nil
+	}
+
+	host, port, err := net.SplitHostPort(broker)
+	if err != nil {
+		report.Steps = append(report.Steps, DiagnoseStep{Name: StepDNS, Err: err})
+		return report
+	}
+
+	ok := step(StepDNS, func(ctx context.Context) (err error) {
+		report.Addrs, err = net.DefaultResolver.LookupHost(ctx, host)
+		return
+	})
+	if !ok {
+		return report
+	}
+
+	// 只检查第一个解析地址，与kafka客户端的行为一致
+	addr := net.JoinHostPort(report.Addrs[0], port)
+	var conn net.Conn
+	ok = step(StepDial, func(ctx context.Context) (err error) {
+		conn, err = (&net.Dialer{}).DialContext(ctx, "tcp", addr)
+		return
+	})
+	if !ok {
+		return report
+	}
+
+	if dialer.TLS != nil {
+		step(StepTLS, func(ctx context.Context) error {
+			cfg := dialer.TLS.Clone()
+			if cfg.ServerName == "" {
+				cfg.ServerName = host
+			}
+			return tls.Client(conn, cfg).HandshakeContext(ctx)
+		})
+	}
+	conn.Close()
+	if report.FailedStep() != nil {
+		return report
+	}
+
+	step(StepAPIVersions, func(ctx context.Context) error {
+		kc, err := dialer.DialContext(ctx, "tcp", broker)
+		if err != nil {
+			return err
+		}
+		defer kc.Close()
+		if deadline, ok := ctx.Deadline(); ok {
+			kc.SetDeadline(deadline)
+		}
+		report.APIVersions, err = kc.ApiVersions()
+		return err
+	})
+	return report
+}
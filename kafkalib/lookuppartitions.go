@@ -3,8 +3,10 @@ package kafkalib
 import (
 	"context"
 	"errors"
+	"fmt"
 	"time"
 
+	"github.com/cdpzyafk/go-utils/common"
 	"github.com/segmentio/kafka-go"
 	"go.uber.org/zap"
 )
@@ -15,21 +17,26 @@ var (
 
 // LookupPartitions 轮询所有broker,查找对应topic的所有partions
 func LookupPartitions(log *zap.Logger, brokers []string, topic string) ([]kafka.Partition, error) {
-	for _, addr := range brokers {
-		if partitions, err := lookupPartitions(addr, topic); err == nil {
-			return partitions, nil
-		} else {
-			log.Error("lookupPartions failed", zap.Error(err),
-				zap.String("addr", addr),
-				zap.String("topic", topic))
-		}
-	}
-
-	return nil, ErrNonePartionFound
+	return LookupPartitionsWithDialer(log, kafka.DefaultDialer, brokers, topic)
 }
 
-func lookupPartitions(addr, topic string) ([]kafka.Partition, error) {
-	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
-	defer cancel()
-	return kafka.LookupPartitions(ctx, "tcp", addr, topic)
+// LookupPartitionsWithDialer 同LookupPartitions，使用指定Dialer连接broker，需要TLS/SASL时用NewDialer创建
+func LookupPartitionsWithDialer(log *zap.Logger, dialer *kafka.Dialer, brokers []string, topic string) ([]kafka.Partition, error) {
+	if dialer == nil {
+		dialer = kafka.DefaultDialer
+	}
+	partitions, err := common.Fallback(context.Background(), brokers, 3*time.Second,
+		func(ctx context.Context, addr string) ([]kafka.Partition, error) {
+			partitions, err := dialer.LookupPartitions(ctx, "tcp", addr, topic)
+			if err != nil {
+				log.Error("lookupPartions failed", zap.Error(err),
+					zap.String("addr", addr),
+					zap.String("topic", topic))
+			}
+			return partitions, err
+		})
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrNonePartionFound, err)
+	}
+	return partitions, nil
 }
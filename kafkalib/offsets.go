@@ -0,0 +1,49 @@
+package kafkalib
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/segmentio/kafka-go"
+)
+
+// OffsetRange 分区当前可用的offset范围
+type OffsetRange struct {
+	First int64 // 最早可用的offset
+	Last  int64 // 高水位，即下一条待写入的offset
+}
+
+// NewTransport 沿用dialer的超时和TLS/SASL配置，dialer为nil时使用明文连接。
+// Transport会在后台定期刷新元数据，不再使用时需调用CloseIdleConnections释放
+func NewTransport(dialer *kafka.Dialer) *kafka.Transport {
+	transport := &kafka.Transport{}
+	if dialer != nil {
+		transport.DialTimeout = dialer.Timeout
+		transport.TLS = dialer.TLS
+		transport.SASL = dialer.SASLMechanism
+	}
+	return transport
+}
+
+// ListOffsets 查询topic指定分区的offset范围，client应在多次查询间复用
+func ListOffsets(ctx context.Context, client *kafka.Client, topic string, partitions ...int) (map[int]OffsetRange, error) {
+	requests := make([]kafka.OffsetRequest, 0, len(partitions)*2)
+	for _, p := range partitions {
+		requests = append(requests, kafka.FirstOffsetOf(p), kafka.LastOffsetOf(p))
+	}
+	resp, err := client.ListOffsets(ctx, &kafka.ListOffsetsRequest{
+		Topics: map[string][]kafka.OffsetRequest{topic: requests},
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	offsets := make(map[int]OffsetRange, len(partitions))
+	for _, p := range resp.Topics[topic] {
+		if p.Error != nil {
+			return nil, fmt.Errorf("partition %d: %w", p.Partition, p.Error)
+		}
+		offsets[p.Partition] = OffsetRange{First: p.FirstOffset, Last: p.LastOffset}
+	}
+	return offsets, nil
+}
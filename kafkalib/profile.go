@@ -0,0 +1,137 @@
+package kafkalib
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sync"
+
+	"github.com/segmentio/kafka-go"
+)
+
+// Listener 与broker端listener类型一致
+type Listener string
+
+const (
+	ListenerPlaintext     Listener = "PLAINTEXT"
+	ListenerSSL           Listener = "SSL"
+	ListenerSASLPlaintext Listener = "SASL_PLAINTEXT"
+	ListenerSASLSSL       Listener = "SASL_SSL"
+)
+
+var (
+	ErrProfileNotFound = errors.New("cluster profile not found")
+	ErrInvalidProfile  = errors.New("invalid cluster profile")
+)
+
+// ClusterProfile 描述某个环境(dev/staging/prod)下的kafka集群
+type ClusterProfile struct {
+	Name     string      `json:"name"`
+	Brokers  []string    `json:"brokers"`
+	Listener Listener    `json:"listener"` // default PLAINTEXT
+	TLS      *TLSConfig  `json:"tls,omitempty"`
+	SASL     *SASLConfig `json:"sasl,omitempty"`
+
+	dialerOnce sync.Once
+	dialer     *kafka.Dialer
+	dialerErr  error
+}
+
+func (p *ClusterProfile) Validate() error {
+	if p.Name == "" {
+		return fmt.Errorf("%w: empty name", ErrInvalidProfile)
+	}
+	if len(p.Brokers) == 0 {
+		return fmt.Errorf("%w: %s has no brokers", ErrInvalidProfile, p.Name)
+	}
+	switch p.Listener {
+	case "", ListenerPlaintext, ListenerSSL:
+	case ListenerSASLPlaintext, ListenerSASLSSL:
+		if p.SASL == nil {
+			return fmt.Errorf("%w: %s listener %s requires sasl", ErrInvalidProfile, p.Name, p.Listener)
+		}
+	default:
+		return fmt.Errorf("%w: %s unknown listener %s", ErrInvalidProfile, p.Name, p.Listener)
+	}
+	return nil
+}
+
+// Dialer 按listener类型创建Dialer，同一profile复用同一个Dialer
+func (p *ClusterProfile) Dialer() (*kafka.Dialer, error) {
+	p.dialerOnce.Do(func() {
+		var (
+			tlsCfg  *TLSConfig
+			saslCfg *SASLConfig
+		)
+		switch p.Listener {
+		case ListenerSSL:
+			tlsCfg = p.tlsConfig()
+		case ListenerSASLPlaintext:
+			saslCfg = p.SASL
+		case ListenerSASLSSL:
+			tlsCfg, saslCfg = p.tlsConfig(), p.SASL
+		}
+		p.dialer, p.dialerErr = NewDialer(tlsCfg, saslCfg)
+	})
+	return p.dialer, p.dialerErr
+}
+
+func (p *ClusterProfile) tlsConfig() *TLSConfig {
+	if p.TLS == nil {
+		return &TLSConfig{}
+	}
+	return p.TLS
+}
+
+var (
+	profilesMu sync.RWMutex
+	profiles   = make(map[string]*ClusterProfile)
+)
+
+// RegisterProfile 注册profile，同名会覆盖
+func RegisterProfile(p *ClusterProfile) error {
+	if err := p.Validate(); err != nil {
+		return err
+	}
+	profilesMu.Lock()
+	defer profilesMu.Unlock()
+	profiles[p.Name] = p
+	return nil
+}
+
+func GetProfile(name string) (*ClusterProfile, error) {
+	profilesMu.RLock()
+	defer profilesMu.RUnlock()
+	if p, ok := profiles[name]; ok {
+		return p, nil
+	}
+	return nil, fmt.Errorf("%w: %s", ErrProfileNotFound, name)
+}
+
+// LoadProfiles 从json数组读取并注册profile
+func LoadProfiles(r io.Reader) error {
+	var list []*ClusterProfile
+	if err := json.NewDecoder(r).Decode(&list); err != nil {
+		return err
+	}
+	for _, p := range list {
+		if err := p.Validate(); err != nil {
+			return err
+		}
+	}
+	for _, p := range list {
+		_ = RegisterProfile(p)
+	}
+	return nil
+}
+
+func LoadProfilesFile(path string) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	return LoadProfiles(f)
+}
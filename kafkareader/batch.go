@@ -0,0 +1,66 @@
+package kafkareader
+
+import (
+	"time"
+
+	"github.com/segmentio/kafka-go"
+)
+
+const (
+	MAXBATCHSIZE = 100
+	MAXBATCHWAIT = time.Second
+)
+
+// batch 单个分区待交给BatchHandler的消息
+type batch struct {
+	msgs     []kafka.Message
+	size     int
+	wait     time.Duration
+	deadline time.Time // 第一条消息加入后wait时间
+}
+
+func newBatch(size int, wait time.Duration) *batch {
+	return &batch{msgs: make([]kafka.Message, 0, size), size: size, wait: wait}
+}
+
+// addToBatch 加入消息，达到MaxBatchSize时立即投递
+func (pr *PartitionReader) addToBatch(msg kafka.Message) {
+	b := pr.batch
+	if len(b.msgs) == 0 {
+		b.deadline = time.Now().Add(b.wait)
+	}
+	b.msgs = append(b.msgs, msg)
+	if len(b.msgs) >= b.size {
+		pr.flushBatch()
+	}
+}
+
+// flushDueBatch 投递已等待超过MaxBatchWait的批次
+func (pr *PartitionReader) flushDueBatch() {
+	if b := pr.batch; b != nil && len(b.msgs) > 0 && !time.Now().Before(b.deadline) {
+		pr.flushBatch()
+	}
+}
+
+func (pr *PartitionReader) flushBatch() {
+	b := pr.batch
+	if b == nil || len(b.msgs) == 0 {
+		return
+	}
+	msgs := b.msgs
+	// handler可能持有切片，每批使用新的切片
+	b.msgs = make([]kafka.Message, 0, b.size)
+	start := time.Now()
+	pr.parent.batchHandler(pr.log, msgs)
+	pr.parent.metrics.HandlerDone(pr.parent.topic, pr.partition.ID, time.Since(start), nil)
+	pr.parent.markHandled(msgs[len(msgs)-1])
+}
+
+// fetchTimeout 有待投递的批次时拉取不超过其剩余等待时间
+func (pr *PartitionReader) fetchTimeout() time.Duration {
+	timeout := HEARTBEATEVERY
+	if b := pr.batch; b != nil && len(b.msgs) > 0 {
+		timeout = min(timeout, max(time.Until(b.deadline), time.Millisecond))
+	}
+	return timeout
+}
@@ -0,0 +1,63 @@
+package kafkareader
+
+import (
+	"testing"
+	"time"
+
+	"github.com/segmentio/kafka-go"
+	"go.uber.org/zap"
+)
+
+func TestBatchFlush(t *testing.T) {
+	r := newTestReader(t, 0)
+	cp := &memCheckpointer{}
+	r.initCheckpoint(cp, time.Minute)
+	var batches [][]int64
+	r.batchHandler = func(_ *zap.Logger, msgs []kafka.Message) {
+		offsets := make([]int64, len(msgs))
+		for i, msg := range msgs {
+			offsets[i] = msg.Offset
+		}
+		batches = append(batches, offsets)
+	}
+	pr := r.readers[0]
+	pr.batch = newBatch(3, 30*time.Millisecond)
+
+	if d := pr.fetchTimeout(); d != HEARTBEATEVERY {
+		t.Fatalf("fetch timeout without pending batch %v", d)
+	}
+	// 达到MaxBatchSize立即投递
+	for offset := int64(0); offset < 4; offset++ {
+		pr.addToBatch(kafka.Message{Offset: offset})
+	}
+	if len(batches) != 1 || len(batches[0]) != 3 || batches[0][2] != 2 {
+		t.Fatalf("batches %v", batches)
+	}
+	if progress := r.handled[0].Load(); progress != 3 {
+		t.Fatalf("progress %d after first batch", progress)
+	}
+
+	// 未到MaxBatchWait不投递，拉取超时不超过剩余等待时间
+	pr.flushDueBatch()
+	if len(batches) != 1 {
+		t.Fatal("flushed before max batch wait")
+	}
+	if d := pr.fetchTimeout(); d > 30*time.Millisecond {
+		t.Fatalf("fetch timeout %v with a pending batch", d)
+	}
+	time.Sleep(40 * time.Millisecond)
+	pr.flushDueBatch()
+	if len(batches) != 2 || len(batches[1]) != 1 || batches[1][0] != 3 {
+		t.Fatalf("batches %v", batches)
+	}
+	if progress := r.handled[0].Load(); progress != 4 {
+		t.Fatalf("progress %d after due batch", progress)
+	}
+
+	// 空批次不投递
+	pr.flushBatch()
+	if len(batches) != 2 {
+		t.Fatal("flushed an empty batch")
+	}
+	r.Close()
+}
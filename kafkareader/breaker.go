@@ -0,0 +1,104 @@
+package kafkareader
+
+import (
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// BreakerState 下游熔断器状态
+type BreakerState int32
+
+const (
+	BreakerClosed BreakerState = iota
+	BreakerOpen
+	BreakerHalfOpen
+)
+
+func (s BreakerState) String() string {
+	switch s {
+	case BreakerClosed:
+		return "closed"
+	case BreakerOpen:
+		return "open"
+	case BreakerHalfOpen:
+		return "half-open"
+	default:
+		return "unknown"
+	}
+}
+
+// Breaker 下游依赖的熔断器，由使用方适配具体实现
+type Breaker interface {
+	State() BreakerState
+}
+
+// BreakerEvent 分区因熔断状态变化而暂停/恢复拉取
+type BreakerEvent struct {
+	Topic     string
+	Partition int
+	From      BreakerState
+	To        BreakerState
+	At        time.Time
+}
+
+type partitionBreaker struct {
+	breaker Breaker
+	poll    time.Duration
+	state   BreakerState
+	probed  bool // 本次半开期间已放行探测消息
+}
+
+// waitBreaker 熔断打开时阻塞；半开时只放行一条消息作为探测，之后阻塞到熔断器关闭或重新打开；
+// 等待期间持续报告心跳，Stop时返回false
+func (pr *PartitionReader) waitBreaker() bool {
+	pb := pr.breaker
+	if pb == nil {
+		return true
+	}
+	for {
+		state := pb.breaker.State()
+		if state != pb.state {
+			pr.emitBreakerEvent(pb.state, state)
+			pb.state, pb.probed = state, false
+		}
+		switch {
+		case state == BreakerClosed:
+			return true
+		case state == BreakerHalfOpen && !pb.probed:
+			pb.probed = true
+			return true
+		}
+		pr.parent.heartbeat.Beat(pr.heartbeatName)
+		if !pr.sleep(pb.poll) {
+			return false
+		}
+	}
+}
+
+func (pr *PartitionReader) emitBreakerEvent(from, to BreakerState) {
+	pr.log.Warn("breaker state changed",
+		zap.Stringer("from", from),
+		zap.Stringer("to", to))
+	if pr.parent.onBreakerEvent != nil {
+		pr.parent.onBreakerEvent(BreakerEvent{
+			Topic:     pr.parent.topic,
+			Partition: pr.partition.ID,
+			From:      from,
+			To:        to,
+			At:        time.Now(),
+		})
+	}
+}
+
+func breakerAffects(partitions []int, id int) bool {
+	if len(partitions) == 0 {
+		return true
+	}
+	for _, p := range partitions {
+		if p == id {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,80 @@
+package kafkareader
+
+import (
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+type testBreaker struct {
+	state atomic.Int32
+}
+
+func (tb *testBreaker) State() BreakerState { return BreakerState(tb.state.Load()) }
+
+func (tb *testBreaker) set(state BreakerState) { tb.state.Store(int32(state)) }
+
+func TestWaitBreakerHalfOpenProbe(t *testing.T) {
+	r := newTestReader(t, 0)
+	tb := &testBreaker{}
+	var events []BreakerEvent
+	r.onBreakerEvent = func(e BreakerEvent) { events = append(events, e) }
+	pr := r.readers[0]
+	pr.breaker = &partitionBreaker{breaker: tb, poll: 5 * time.Millisecond, state: BreakerClosed}
+
+	if !pr.waitBreaker() || !pr.waitBreaker() {
+		t.Fatal("closed breaker blocked")
+	}
+
+	// 半开时只放行一条探测消息
+	tb.set(BreakerHalfOpen)
+	if !pr.waitBreaker() {
+		t.Fatal("half-open breaker blocked the probe")
+	}
+	released := make(chan bool)
+	go func() { released <- pr.waitBreaker() }()
+	select {
+	case <-released:
+		t.Fatal("half-open breaker let a second message through")
+	case <-time.After(30 * time.Millisecond):
+	}
+	tb.set(BreakerClosed)
+	select {
+	case ok := <-released:
+		if !ok {
+			t.Fatal("waitBreaker returned false after the breaker closed")
+		}
+	case <-time.After(time.Second):
+		t.Fatal("not released after the breaker closed")
+	}
+
+	// 再次半开时重新放行一条探测消息，Stop时结束等待
+	tb.set(BreakerOpen)
+	go func() { released <- pr.waitBreaker() }()
+	time.Sleep(20 * time.Millisecond)
+	tb.set(BreakerHalfOpen)
+	if ok := <-released; !ok {
+		t.Fatal("probe not released after reopening to half-open")
+	}
+	go func() { released <- pr.waitBreaker() }()
+	time.Sleep(20 * time.Millisecond)
+	pr.Stop()
+	if ok := <-released; ok {
+		t.Fatal("waitBreaker should return false after Stop")
+	}
+
+	var transitions []BreakerState
+	for _, e := range events {
+		transitions = append(transitions, e.To)
+	}
+	want := []BreakerState{BreakerHalfOpen, BreakerClosed, BreakerOpen, BreakerHalfOpen}
+	if len(transitions) != len(want) {
+		t.Fatalf("transitions %v, want %v", transitions, want)
+	}
+	for i := range want {
+		if transitions[i] != want[i] {
+			t.Fatalf("transitions %v, want %v", transitions, want)
+		}
+	}
+	r.Close()
+}
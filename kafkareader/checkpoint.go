@@ -0,0 +1,247 @@
+package kafkareader
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/segmentio/kafka-go"
+	"go.uber.org/zap"
+)
+
+const (
+	CHECKPOINTEVERY   = time.Second * 5
+	CHECKPOINTTIMEOUT = time.Second * 10
+)
+
+// Checkpointer 持久化各分区已处理到的位置，offset为下一条待处理消息的offset。
+// 除内置的文件和消费组实现外，可自行适配Redis等存储
+type Checkpointer interface {
+	// Load 没有记录时ok为false
+	Load(ctx context.Context, topic string, partition int) (offset int64, ok bool, err error)
+	Save(ctx context.Context, topic string, offsets map[int]int64) error
+}
+
+// FileCheckpointer 将offset以JSON保存在本地文件，多个topic可共用同一文件
+type FileCheckpointer struct {
+	mu   sync.Mutex
+	path string
+}
+
+func NewFileCheckpointer(path string) *FileCheckpointer {
+	return &FileCheckpointer{path: path}
+}
+
+func (fc *FileCheckpointer) Load(_ context.Context, topic string, partition int) (int64, bool, error) {
+	fc.mu.Lock()
+	defer fc.mu.Unlock()
+	all, err := fc.read()
+	if err != nil {
+		return 0, false, err
+	}
+	offset, ok := all[topic][strconv.Itoa(partition)]
+	return offset, ok, nil
+}
+
+func (fc *FileCheckpointer) Save(_ context.Context, topic string, offsets map[int]int64) error {
+	fc.mu.Lock()
+	defer fc.mu.Unlock()
+	all, err := fc.read()
+	if err != nil {
+		return err
+	}
+	if all[topic] == nil {
+		all[topic] = make(map[string]int64, len(offsets))
+	}
+	for partition, offset := range offsets {
+		all[topic][strconv.Itoa(partition)] = offset
+	}
+	data, err := json.MarshalIndent(all, "", "  ")
+	if err != nil {
+		return err
+	}
+	// 先写临时文件再rename，进程中途退出不会留下损坏的文件
+	tmp, err := os.CreateTemp(filepath.Dir(fc.path), filepath.Base(fc.path)+".tmp*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if _, err = tmp.Write(data); err == nil {
+		err = tmp.Sync()
+	}
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), fc.path)
+}
+
+// read 文件不存在时返回空记录
+func (fc *FileCheckpointer) read() (map[string]map[string]int64, error) {
+	all := make(map[string]map[string]int64)
+	data, err := os.ReadFile(fc.path)
+	if errors.Is(err, os.ErrNotExist) {
+		return all, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err = json.Unmarshal(data, &all); err != nil {
+		return nil, fmt.Errorf("checkpoint file %s: %w", fc.path, err)
+	}
+	return all, nil
+}
+
+// GroupCheckpointer 将offset提交到kafka的消费组(__consumer_offsets)，不加入消费组，
+// 分区分配仍由Reader自己决定；提交后可用kafka自带工具查看消费进度
+type GroupCheckpointer struct {
+	client  *kafka.Client
+	groupID string
+}
+
+// NewGroupCheckpointer client需设置Addr，需要认证时通过client.Transport配置
+func NewGroupCheckpointer(client *kafka.Client, groupID string) *GroupCheckpointer {
+	return &GroupCheckpointer{client: client, groupID: groupID}
+}
+
+func (gc *GroupCheckpointer) Load(ctx context.Context, topic string, partition int) (int64, bool, error) {
+	resp, err := gc.client.OffsetFetch(ctx, &kafka.OffsetFetchRequest{
+		GroupID: gc.groupID,
+		Topics:  map[string][]int{topic: {partition}},
+	})
+	if err != nil {
+		return 0, false, err
+	}
+	if resp.Error != nil {
+		return 0, false, resp.Error
+	}
+	for _, p := range resp.Topics[topic] {
+		if p.Partition != partition {
+			continue
+		}
+		if p.Error != nil {
+			return 0, false, p.Error
+		}
+		return p.CommittedOffset, p.CommittedOffset >= 0, nil
+	}
+	return 0, false, nil
+}
+
+func (gc *GroupCheckpointer) Save(ctx context.Context, topic string, offsets map[int]int64) error {
+	commits := make([]kafka.OffsetCommit, 0, len(offsets))
+	for partition, offset := range offsets {
+		commits = append(commits, kafka.OffsetCommit{Partition: partition, Offset: offset})
+	}
+	resp, err := gc.client.OffsetCommit(ctx, &kafka.OffsetCommitRequest{
+		GroupID:      gc.groupID,
+		GenerationID: -1, // 不属于任何generation的独立提交
+		Topics:       map[string][]kafka.OffsetCommit{topic: commits},
+	})
+	if err != nil {
+		return err
+	}
+	var errs []error
+	for _, p := range resp.Topics[topic] {
+		if p.Error != nil {
+			errs = append(errs, fmt.Errorf("partition %d: %w", p.Partition, p.Error))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+func (p *Reader) initCheckpoint(checkpointer Checkpointer, every time.Duration) {
+	p.checkpointer, p.checkpointEvery = checkpointer, every
+	p.checkpointDone = make(chan struct{})
+	p.handled = make(map[int]*atomic.Int64, len(p.partitions))
+	p.held = make(map[int]*atomic.Int64, len(p.partitions))
+	p.saved = make(map[int]int64, len(p.partitions))
+	for _, partition := range p.partitions {
+		p.handled[partition.ID] = &atomic.Int64{}
+		p.handled[partition.ID].Store(-1)
+		p.held[partition.ID] = &atomic.Int64{}
+		p.held[partition.ID].Store(-1)
+	}
+}
+
+// markHandled 记录分区已处理到msg
+func (p *Reader) markHandled(msg kafka.Message) {
+	p.markOffset(msg.Partition, msg.Offset+1)
+}
+
+// markOffset 记录分区下一条待处理的offset
+func (p *Reader) markOffset(partition int, next int64) {
+	if progress, ok := p.handled[partition]; ok {
+		progress.Store(next)
+	}
+}
+
+// holdOffset msg既未处理成功也未转交出去(如Close时放弃等待或重试)，
+// 分区保存的进度不再越过msg，重启后从msg重新处理
+func (p *Reader) holdOffset(msg kafka.Message) {
+	held, ok := p.held[msg.Partition]
+	if !ok {
+		return
+	}
+	for {
+		cur := held.Load()
+		if cur >= 0 && cur <= msg.Offset {
+			return
+		}
+		if held.CompareAndSwap(cur, msg.Offset) {
+			return
+		}
+	}
+}
+
+// runCheckpoint 定期保存各分区的处理进度，Close时停止
+func (p *Reader) runCheckpoint(every time.Duration) {
+	defer close(p.checkpointDone)
+	ticker := time.NewTicker(every)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-p.ctx.Done():
+			return
+		case <-ticker.C:
+			p.saveCheckpoint()
+		}
+	}
+}
+
+// saveCheckpoint 只保存自上次保存后有变化的分区
+func (p *Reader) saveCheckpoint() {
+	p.checkpointMu.Lock()
+	defer p.checkpointMu.Unlock()
+
+	offsets := make(map[int]int64)
+	for partition, progress := range p.handled {
+		offset := progress.Load()
+		if held := p.held[partition].Load(); held >= 0 && offset > held {
+			offset = held
+		}
+		if offset >= 0 && offset != p.saved[partition] {
+			offsets[partition] = offset
+		}
+	}
+	if len(offsets) == 0 {
+		return
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), CHECKPOINTTIMEOUT)
+	defer cancel()
+	if err := p.checkpointer.Save(ctx, p.topic, offsets); err != nil {
+		p.log.Error("save checkpoint failed", zap.Error(err))
+		return
+	}
+	for partition, offset := range offsets {
+		p.saved[partition] = offset
+	}
+}
@@ -0,0 +1,100 @@
+package kafkareader
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/segmentio/kafka-go"
+)
+
+func TestFileCheckpointer(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "offsets.json")
+	fc := NewFileCheckpointer(path)
+	ctx := context.Background()
+
+	if _, ok, err := fc.Load(ctx, "orders", 0); ok || err != nil {
+		t.Fatalf("load from missing file ok %v err %v", ok, err)
+	}
+	if err := fc.Save(ctx, "orders", map[int]int64{0: 10, 1: 20}); err != nil {
+		t.Fatal(err)
+	}
+	// 同一topic合并分区，不同topic共用文件
+	if err := fc.Save(ctx, "orders", map[int]int64{1: 21}); err != nil {
+		t.Fatal(err)
+	}
+	if err := fc.Save(ctx, "payments", map[int]int64{0: 5}); err != nil {
+		t.Fatal(err)
+	}
+
+	fc = NewFileCheckpointer(path)
+	for _, tc := range []struct {
+		topic     string
+		partition int
+		offset    int64
+		ok        bool
+	}{
+		{"orders", 0, 10, true},
+		{"orders", 1, 21, true},
+		{"orders", 2, 0, false},
+		{"payments", 0, 5, true},
+		{"unknown", 0, 0, false},
+	} {
+		offset, ok, err := fc.Load(ctx, tc.topic, tc.partition)
+		if err != nil || ok != tc.ok || offset != tc.offset {
+			t.Fatalf("%s/%d: offset %d ok %v err %v", tc.topic, tc.partition, offset, ok, err)
+		}
+	}
+
+	// rename后不留下临时文件
+	entries, _ := os.ReadDir(dir)
+	if len(entries) != 1 {
+		t.Fatalf("unexpected files %v", entries)
+	}
+
+	if err := os.WriteFile(path, []byte("{"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if _, _, err := fc.Load(ctx, "orders", 0); err == nil {
+		t.Fatal("expected error for a corrupted file")
+	}
+}
+
+func TestSaveCheckpoint(t *testing.T) {
+	r := newTestReader(t, 0, 1, 2)
+	cp := &memCheckpointer{}
+	r.initCheckpoint(cp, time.Minute)
+
+	r.saveCheckpoint()
+	if len(cp.saves) != 0 {
+		t.Fatal("saved before any message was handled")
+	}
+
+	r.markHandled(kafka.Message{Partition: 0, Offset: 9})
+	r.markHandled(kafka.Message{Partition: 1, Offset: 19})
+	r.saveCheckpoint()
+	r.markHandled(kafka.Message{Partition: 1, Offset: 20})
+	r.saveCheckpoint()
+	r.saveCheckpoint()
+
+	if len(cp.saves) != 2 {
+		t.Fatalf("saves %v", cp.saves)
+	}
+	if first := cp.saves[0]; len(first) != 2 || first[0] != 10 || first[1] != 20 {
+		t.Fatalf("first save %v", first)
+	}
+	// 只保存有变化的分区
+	if second := cp.saves[1]; len(second) != 1 || second[1] != 21 {
+		t.Fatalf("second save %v", second)
+	}
+
+	// Close时保存最后的进度
+	r.markHandled(kafka.Message{Partition: 2, Offset: 0})
+	r.Close()
+	if last := cp.saves[len(cp.saves)-1]; len(last) != 1 || last[2] != 1 {
+		t.Fatalf("save on close %v", last)
+	}
+}
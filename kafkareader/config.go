@@ -3,6 +3,8 @@ package kafkareader
 import (
 	"time"
 
+	"github.com/cdpzyafk/go-utils/common"
+	"github.com/cdpzyafk/go-utils/kafkalib"
 	"github.com/segmentio/kafka-go"
 	"go.uber.org/zap"
 )
@@ -11,14 +13,60 @@ const (
 	MINBYTES       = 512
 	MAXBYTES       = 1024 * 1024 * 4
 	READBACKOFFMIN = time.Millisecond * 100
+	BREAKERPOLL    = time.Second
+	HEARTBEATEVERY = time.Second * 10
+	RECOVERMIN     = time.Millisecond * 200 // reader出错后首次重建前的等待
+	RECOVERMAX     = time.Second * 30
 )
 
 type Config struct {
 	Name           string
+	Profile        string // kafkalib中注册的集群profile名，Brokers为空时使用profile的brokers
 	Brokers        []string
+	TLS            *kafkalib.TLSConfig  // 非nil时启用TLS，设置后覆盖Profile的认证配置
+	SASL           *kafkalib.SASLConfig // 非nil时启用SASL(PLAIN/SCRAM)，设置后覆盖Profile的认证配置
 	Topic          string
 	MinBytes       int           // default MINBYTES
 	MaxBytes       int           // default MAXBYTES
 	ReadBackoffMin time.Duration // default READBACKOFFMIN
 	Handler        func(*zap.Logger, kafka.Message)
+	HandlerE       func(*zap.Logger, kafka.Message) error // 返回错误的消息交给DeadLetter
+	BatchHandler   func(*zap.Logger, []kafka.Message)     // 同一批消息来自同一分区
+	MaxBatchSize   int                                    // default MAXBATCHSIZE
+	MaxBatchWait   time.Duration                          // 批次中第一条消息最多等待的时间, default MAXBATCHWAIT
+	StartOffset    StartOffset                            // default StartAtLast()
+	Filter         Filter                                 // 在handler之前执行，返回false的消息被跳过
+	Dedupe         *common.DedupeStore                    // 非nil时跳过TTL内key重复的消息，由调用方打开和关闭
+	DedupeKey      DedupeKey                              // default MessageKey
+
+	DeadLetter DeadLetter   // HandlerE失败的消息写入的位置，为空时只记录日志
+	Retry      *RetryPolicy // 非nil时HandlerE失败后先在本地重试
+
+	Concurrency int  // 每个分区并发调用Handler的worker数，慢handler不再阻塞整个分区, default 1
+	KeyOrdered  bool // Concurrency>1时同一key的消息由同一worker按顺序处理
+	// BufferSize 每个分区拉取与处理之间缓冲的消息数上限，拉取可领先处理至多这么多条，
+	// 缓冲满时暂停拉取；0表示在拉取goroutine中同步处理(Concurrency>1时为WORKERQUEUE)
+	BufferSize int
+
+	MaxMessagesPerSecond float64 // >0时限制处理速率，避免回放历史数据时压垮下游
+	RateBurst            int     // 允许的突发条数, default 向上取整的MaxMessagesPerSecond
+	RatePerReader        bool    // 所有分区共用MaxMessagesPerSecond，默认每个分区各自限制
+
+	Breaker           Breaker            // 下游熔断器，打开时暂停拉取
+	BreakerPartitions []int              // 受熔断器影响的分区，为空表示全部分区
+	BreakerPoll       time.Duration      // 熔断打开期间检查状态的间隔, default BREAKERPOLL
+	OnBreakerEvent    func(BreakerEvent) // 分区暂停/恢复事件回调
+
+	Watermark *WatermarkConfig // 非nil时按事件时间跨分区对齐投递
+	Ordered   *OrderedConfig   // 非nil时所有分区的消息按时间戳归并后串行投递
+
+	Checkpointer    Checkpointer  // 非nil时定期保存处理进度，StartOffset为空时从保存的位置继续
+	CheckpointEvery time.Duration // default CHECKPOINTEVERY
+
+	LagLogEvery time.Duration    // >0时定期查询并记录各分区lag
+	Metrics     MetricsCollector // 消费指标，如NewPrometheusMetrics
+
+	// Heartbeat 各分区拉取循环的心跳，名字为"<Name>/<分区>"，未设置Name时为"<Topic>#<序号>/<分区>"；
+	// 同一Heartbeat上未关闭的Reader不能重名, default common.DefaultHeartbeat
+	Heartbeat *common.Heartbeat
 }
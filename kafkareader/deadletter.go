@@ -0,0 +1,185 @@
+package kafkareader
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"os"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/cdpzyafk/go-utils/common"
+	"github.com/cdpzyafk/go-utils/kafkalib"
+	"github.com/segmentio/kafka-go"
+	"go.uber.org/zap"
+)
+
+const (
+	HeaderOriginalPartition = "x-original-partition"
+	HeaderOriginalOffset    = "x-original-offset"
+	HeaderFailedAt          = "x-failed-at" // unix毫秒
+)
+
+// DeadLetter 接收HandlerE处理失败的消息
+type DeadLetter interface {
+	Send(ctx context.Context, msg kafka.Message, cause error) error
+}
+
+// TopicDeadLetter 将失败的消息写入死信topic，失败信息保存在header中
+type TopicDeadLetter struct {
+	writer    *kafka.Writer
+	transport *kafka.Transport // Writer.Close不会释放外部传入的Transport
+	topic     string
+}
+
+// NewTopicDeadLetter dialer可选，用于TLS/SASL
+func NewTopicDeadLetter(brokers []string, dialer *kafka.Dialer, topic string) (*TopicDeadLetter, error) {
+	if len(brokers) == 0 {
+		return nil, ErrNoBrokers
+	}
+	if topic == "" {
+		return nil, ErrNoTopic
+	}
+	transport := kafkalib.NewTransport(dialer)
+	return &TopicDeadLetter{
+		writer: &kafka.Writer{
+			Addr:         kafka.TCP(brokers...),
+			Balancer:     &kafka.Hash{},
+			RequiredAcks: kafka.RequireAll,
+			Transport:    transport,
+		},
+		transport: transport,
+		topic:     topic,
+	}, nil
+}
+
+func (td *TopicDeadLetter) Send(ctx context.Context, msg kafka.Message, cause error) error {
+	headers := setHeader(msg.Headers, HeaderError, cause.Error())
+	if _, ok := header(msg, HeaderOriginalTopic); !ok {
+		headers = setHeader(headers, HeaderOriginalTopic, msg.Topic)
+	}
+	headers = setHeader(headers, HeaderOriginalPartition, strconv.Itoa(msg.Partition))
+	headers = setHeader(headers, HeaderOriginalOffset, strconv.FormatInt(msg.Offset, 10))
+	headers = setHeader(headers, HeaderFailedAt, strconv.FormatInt(time.Now().UnixMilli(), 10))
+	return td.writer.WriteMessages(ctx, kafka.Message{
+		Topic:   td.topic,
+		Key:     msg.Key,
+		Value:   msg.Value,
+		Headers: headers,
+	})
+}
+
+func (td *TopicDeadLetter) Close() error {
+	err := td.writer.Close()
+	td.transport.CloseIdleConnections()
+	return err
+}
+
+// DeadLetterRecord FileDeadLetter中每行保存的内容
+type DeadLetterRecord struct {
+	Topic     string            `json:"topic"`
+	Partition int               `json:"partition"`
+	Offset    int64             `json:"offset"`
+	Key       []byte            `json:"key,omitempty"`
+	Value     []byte            `json:"value"`
+	Headers   map[string]string `json:"headers,omitempty"`
+	Time      time.Time         `json:"time"`
+	Error     string            `json:"error"`
+	FailedAt  time.Time         `json:"failedAt"`
+}
+
+// FileDeadLetter 将失败的消息以JSON Lines追加到本地文件，适用于没有死信topic的场景
+type FileDeadLetter struct {
+	mu   sync.Mutex
+	file *os.File
+	enc  *json.Encoder
+}
+
+func NewFileDeadLetter(path string) (*FileDeadLetter, error) {
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
+	if err != nil {
+		return nil, err
+	}
+	return &FileDeadLetter{file: file, enc: json.NewEncoder(file)}, nil
+}
+
+func (fd *FileDeadLetter) Send(_ context.Context, msg kafka.Message, cause error) error {
+	record := DeadLetterRecord{
+		Topic:     msg.Topic,
+		Partition: msg.Partition,
+		Offset:    msg.Offset,
+		Key:       msg.Key,
+		Value:     msg.Value,
+		Time:      msg.Time,
+		Error:     cause.Error(),
+		FailedAt:  time.Now(),
+	}
+	if len(msg.Headers) > 0 {
+		record.Headers = make(map[string]string, len(msg.Headers))
+		for _, h := range msg.Headers {
+			record.Headers[h.Key] = string(h.Value)
+		}
+	}
+	fd.mu.Lock()
+	defer fd.mu.Unlock()
+	return fd.enc.Encode(record)
+}
+
+func (fd *FileDeadLetter) Close() error {
+	fd.mu.Lock()
+	defer fd.mu.Unlock()
+	return fd.file.Close()
+}
+
+// fallible 将HandlerE适配为内部handler，按RetryPolicy重试后仍失败的消息交给DeadLetter
+func (p *Reader) fallible(h func(*zap.Logger, kafka.Message) error) func(*zap.Logger, kafka.Message) {
+	return func(log *zap.Logger, msg kafka.Message) {
+		start := time.Now()
+		var err error
+		if p.retry != nil {
+			err = p.retry.call(p.ctx, log, msg, h)
+		} else {
+			err = h(log, msg)
+		}
+		p.metrics.HandlerDone(p.topic, msg.Partition, time.Since(start), err)
+		if errors.Is(err, errRetryAborted) {
+			// Close时放弃重试，重启后重新处理而不是交给DeadLetter
+			log.Warn("handle message retry abandoned on close", zap.Int64("offset", msg.Offset), zap.Error(err))
+			p.holdOffset(msg)
+		} else if err != nil {
+			p.deadLetter(log, msg, err)
+		}
+	}
+}
+
+// deadLetter 未设置DeadLetter时只记录日志。发送失败时退避重试直到成功，期间阻塞所在分区；
+// Close时仍未发送成功的消息不计入处理进度，重启后重新处理
+func (p *Reader) deadLetter(log *zap.Logger, msg kafka.Message, cause error) {
+	log = log.With(zap.Int64("offset", msg.Offset), zap.Error(cause))
+	if p.dlq == nil {
+		log.Error("handle message failed, message dropped")
+		return
+	}
+	var backoff *common.Backoff
+	for {
+		// 不随Close取消，Close时正在处理的消息仍可发送
+		ctx, cancel := context.WithTimeout(context.Background(), FORWARDTIMEOUT)
+		err := p.dlq.Send(ctx, msg, cause)
+		cancel()
+		if err == nil {
+			log.Warn("handle message failed, sent to dead letter")
+			return
+		}
+		if backoff == nil {
+			backoff = common.NewBackoff(RETRYBACKOFFMIN, RETRYBACKOFFMAX).WithJitter(common.JitterEqual, nil)
+		}
+		wait := backoff.Next()
+		log.Error("send to dead letter failed, retrying", zap.Duration("wait", wait), zap.NamedError("sendErr", err))
+		if p.ctx.Err() != nil || !p.delay(msg.Partition, wait) {
+			log.Error("send to dead letter abandoned on close")
+			p.holdOffset(msg)
+			return
+		}
+	}
+}
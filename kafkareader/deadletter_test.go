@@ -0,0 +1,155 @@
+package kafkareader
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/segmentio/kafka-go"
+	"go.uber.org/zap"
+)
+
+func TestFileDeadLetter(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "dlq.jsonl")
+	fd, err := NewFileDeadLetter(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	msgs := []kafka.Message{
+		{Topic: "orders", Partition: 1, Offset: 10, Key: []byte("k"), Value: []byte(`{"id":1}`),
+			Headers: []kafka.Header{{Key: "trace", Value: []byte("abc")}}},
+		{Topic: "orders", Partition: 2, Offset: 20, Value: []byte("raw")},
+	}
+	for _, msg := range msgs {
+		if err = fd.Send(context.Background(), msg, errors.New("boom")); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err = fd.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	// 重新打开时追加
+	fd, err = NewFileDeadLetter(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = fd.Send(context.Background(), kafka.Message{Offset: 30}, errors.New("again")); err != nil {
+		t.Fatal(err)
+	}
+	fd.Close()
+
+	file, err := os.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+	var records []DeadLetterRecord
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		var record DeadLetterRecord
+		if err = json.Unmarshal(scanner.Bytes(), &record); err != nil {
+			t.Fatal(err)
+		}
+		records = append(records, record)
+	}
+	if len(records) != 3 {
+		t.Fatalf("got %d records", len(records))
+	}
+	first := records[0]
+	if first.Topic != "orders" || first.Partition != 1 || first.Offset != 10 || string(first.Key) != "k" ||
+		string(first.Value) != `{"id":1}` || first.Headers["trace"] != "abc" || first.Error != "boom" || first.FailedAt.IsZero() {
+		t.Fatalf("unexpected record %+v", first)
+	}
+	if records[1].Headers != nil || records[2].Offset != 30 || records[2].Error != "again" {
+		t.Fatalf("unexpected records %+v", records[1:])
+	}
+}
+
+// flakyDeadLetter 前failures次发送失败，failures<0时总是失败
+type flakyDeadLetter struct {
+	mu       sync.Mutex
+	failures int
+	attempts int
+	sent     []int64
+}
+
+func (fd *flakyDeadLetter) Send(_ context.Context, msg kafka.Message, _ error) error {
+	fd.mu.Lock()
+	defer fd.mu.Unlock()
+	fd.attempts++
+	if fd.failures < 0 || fd.attempts <= fd.failures {
+		return errors.New("dead letter unavailable")
+	}
+	fd.sent = append(fd.sent, msg.Offset)
+	return nil
+}
+
+func TestFallible(t *testing.T) {
+	failOdd := func(_ *zap.Logger, msg kafka.Message) error {
+		if msg.Offset%2 == 1 {
+			return errors.New("odd")
+		}
+		return nil
+	}
+
+	t.Run("retry send", func(t *testing.T) {
+		r := newTestReader(t, 0)
+		cp := &memCheckpointer{}
+		r.initCheckpoint(cp, time.Minute)
+		dlq := &flakyDeadLetter{failures: 2}
+		r.dlq = dlq
+		r.handleEvent = r.fallible(failOdd)
+
+		for offset := int64(0); offset < 3; offset++ {
+			r.handle(zap.NewNop(), kafka.Message{Offset: offset})
+		}
+		if dlq.attempts != 3 || len(dlq.sent) != 1 || dlq.sent[0] != 1 {
+			t.Fatalf("attempts %d sent %v", dlq.attempts, dlq.sent)
+		}
+		r.Close()
+		if cp.offsets[0] != 3 {
+			t.Fatalf("saved offset %d, want 3", cp.offsets[0])
+		}
+	})
+
+	t.Run("abandon on close", func(t *testing.T) {
+		r := newTestReader(t, 0)
+		cp := &memCheckpointer{}
+		r.initCheckpoint(cp, time.Minute)
+		r.dlq = &flakyDeadLetter{failures: -1}
+		r.handleEvent = r.fallible(failOdd)
+
+		r.handle(zap.NewNop(), kafka.Message{Offset: 0})
+		time.AfterFunc(50*time.Millisecond, r.cancel)
+		start := time.Now()
+		r.handle(zap.NewNop(), kafka.Message{Offset: 1})
+		if d := time.Since(start); d > 5*time.Second {
+			t.Fatalf("dead letter retry ignored Close for %v", d)
+		}
+		r.handle(zap.NewNop(), kafka.Message{Offset: 2})
+		r.Close()
+		if cp.offsets[0] != 1 {
+			t.Fatalf("saved offset %d, want the unsent message 1", cp.offsets[0])
+		}
+	})
+
+	t.Run("no dead letter", func(t *testing.T) {
+		r := newTestReader(t, 0)
+		cp := &memCheckpointer{}
+		r.initCheckpoint(cp, time.Minute)
+		r.handleEvent = r.fallible(failOdd)
+
+		r.handle(zap.NewNop(), kafka.Message{Offset: 1})
+		r.Close()
+		if cp.offsets[0] != 2 {
+			t.Fatalf("saved offset %d, want 2", cp.offsets[0])
+		}
+	})
+}
@@ -0,0 +1,35 @@
+package kafkareader
+
+import (
+	"github.com/segmentio/kafka-go"
+	"go.uber.org/zap"
+)
+
+// DedupeKey 返回消息的去重key，返回空字符串的消息不参与去重
+type DedupeKey func(kafka.Message) string
+
+// MessageKey 以消息key去重(默认)
+func MessageKey(msg kafka.Message) string {
+	return string(msg.Key)
+}
+
+// duplicate key在Dedupe的TTL内出现过；key在交给handler之前记录，存储出错时照常处理
+func (pr *PartitionReader) duplicate(msg kafka.Message) bool {
+	p := pr.parent
+	if p.dedupe == nil {
+		return false
+	}
+	key := p.dedupeKey(msg)
+	if key == "" {
+		return false
+	}
+	seen, err := p.dedupe.SeenRecently(key)
+	if err != nil {
+		pr.log.Warn("dedupe store failed", zap.Error(err), zap.Int64("offset", msg.Offset))
+		return false
+	}
+	if seen {
+		pr.log.Debug("duplicate message skipped", zap.Int64("offset", msg.Offset), zap.String("key", key))
+	}
+	return seen
+}
@@ -0,0 +1,39 @@
+package kafkareader
+
+import (
+	"testing"
+	"time"
+
+	"github.com/cdpzyafk/go-utils/common"
+	"github.com/segmentio/kafka-go"
+)
+
+func TestDuplicate(t *testing.T) {
+	ds, err := common.OpenDedupeStore(common.DedupeConfig{TTL: time.Hour})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ds.Close()
+	r := newTestReader(t, 0)
+	defer r.Close()
+	pr := r.readers[0]
+	if pr.duplicate(kafka.Message{Key: []byte("a")}) {
+		t.Fatal("duplicate without a dedupe store")
+	}
+
+	r.dedupe, r.dedupeKey = ds, MessageKey
+	for _, tc := range []struct {
+		key  string
+		want bool
+	}{
+		{"a", false},
+		{"b", false},
+		{"a", true},
+		{"", false}, // 没有key的消息不去重
+		{"", false},
+	} {
+		if got := pr.duplicate(kafka.Message{Key: []byte(tc.key)}); got != tc.want {
+			t.Fatalf("key %q duplicate %v, want %v", tc.key, got, tc.want)
+		}
+	}
+}
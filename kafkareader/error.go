@@ -8,4 +8,14 @@ var (
 	ErrNoBrokers = errors.New("no brokers")
 	ErrNoTopic   = errors.New("no topic")
 	ErrNoHandler = errors.New("no handler")
+
+	ErrDuplicateName = errors.New("reader name already in use")
+
+	ErrHandlerConflict = errors.New("only one of Handler, HandlerE and BatchHandler can be set")
+	ErrBatchOrdered    = errors.New("ordered delivery does not support BatchHandler")
+	ErrConcurrency     = errors.New("Concurrency and BufferSize only apply to Handler without ordered delivery")
+
+	ErrInvalidTuning    = errors.New("invalid tuning")
+	ErrUnknownPartition = errors.New("unknown partition")
+	ErrDecode           = errors.New("decode message failed")
 )
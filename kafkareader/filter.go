@@ -0,0 +1,76 @@
+package kafkareader
+
+import (
+	"bytes"
+
+	"github.com/segmentio/kafka-go"
+)
+
+// Filter 返回false的消息不交给handler
+type Filter func(kafka.Message) bool
+
+// HeaderEquals header key存在且值等于value
+func HeaderEquals(key, value string) Filter {
+	return func(msg kafka.Message) bool {
+		v, ok := header(msg, key)
+		return ok && v == value
+	}
+}
+
+// HasHeader header key存在
+func HasHeader(key string) Filter {
+	return func(msg kafka.Message) bool {
+		_, ok := header(msg, key)
+		return ok
+	}
+}
+
+// KeyPrefix 消息key以prefix开头
+func KeyPrefix(prefix string) Filter {
+	p := []byte(prefix)
+	return func(msg kafka.Message) bool {
+		return bytes.HasPrefix(msg.Key, p)
+	}
+}
+
+// AllOf 所有filter都通过
+func AllOf(filters ...Filter) Filter {
+	return func(msg kafka.Message) bool {
+		for _, f := range filters {
+			if !f(msg) {
+				return false
+			}
+		}
+		return true
+	}
+}
+
+// AnyOf 任一filter通过
+func AnyOf(filters ...Filter) Filter {
+	return func(msg kafka.Message) bool {
+		for _, f := range filters {
+			if f(msg) {
+				return true
+			}
+		}
+		return false
+	}
+}
+
+// skip 跳过被过滤的消息，不会越过仍在处理中的消息推进进度
+func (pr *PartitionReader) skip(msg kafka.Message) {
+	if wm := pr.parent.watermark; wm != nil {
+		wm.advance(pr.partition.ID, msg.Time)
+	}
+	switch {
+	case pr.workers != nil:
+		pr.workers.tracker.add(msg.Offset)
+		if next, ok := pr.workers.tracker.done(msg.Offset); ok {
+			pr.parent.markOffset(pr.partition.ID, next)
+		}
+	case pr.batch != nil && len(pr.batch.msgs) > 0, pr.parent.merger != nil:
+		// 更早的消息尚未处理，等其处理完后再推进
+	default:
+		pr.parent.markHandled(msg)
+	}
+}
@@ -0,0 +1,94 @@
+package kafkareader
+
+import (
+	"testing"
+	"time"
+
+	"github.com/segmentio/kafka-go"
+)
+
+func TestFilters(t *testing.T) {
+	msg := kafka.Message{
+		Key:     []byte("order-1"),
+		Headers: []kafka.Header{{Key: "type", Value: []byte("created")}},
+	}
+	for _, tc := range []struct {
+		name   string
+		filter Filter
+		want   bool
+	}{
+		{"header equals", HeaderEquals("type", "created"), true},
+		{"header differs", HeaderEquals("type", "deleted"), false},
+		{"has header", HasHeader("type"), true},
+		{"missing header", HasHeader("trace"), false},
+		{"key prefix", KeyPrefix("order-"), true},
+		{"other prefix", KeyPrefix("user-"), false},
+		{"all of", AllOf(HasHeader("type"), KeyPrefix("order-")), true},
+		{"all of failing", AllOf(HasHeader("type"), KeyPrefix("user-")), false},
+		{"any of", AnyOf(HasHeader("trace"), KeyPrefix("order-")), true},
+		{"any of empty", AnyOf(), false},
+	} {
+		if got := tc.filter(msg); got != tc.want {
+			t.Errorf("%s: got %v, want %v", tc.name, got, tc.want)
+		}
+	}
+}
+
+func TestSkipProgress(t *testing.T) {
+	for _, tc := range []struct {
+		name  string
+		setup func(*PartitionReader)
+		want  int64 // skip offset 5后的进度
+	}{
+		{
+			name: "synchronous",
+			want: 6,
+		},
+		{
+			name: "pending batch",
+			setup: func(pr *PartitionReader) {
+				pr.batch = newBatch(10, time.Minute)
+				pr.addToBatch(kafka.Message{Offset: 4})
+			},
+			want: -1,
+		},
+		{
+			name:  "empty batch",
+			setup: func(pr *PartitionReader) { pr.batch = newBatch(10, time.Minute) },
+			want:  6,
+		},
+		{
+			name:  "ordered",
+			setup: func(pr *PartitionReader) { pr.parent.merger = &merger{} },
+			want:  -1,
+		},
+		{
+			name: "workers with message in flight",
+			setup: func(pr *PartitionReader) {
+				pr.workers = &partitionWorkers{}
+				pr.workers.tracker.add(4)
+			},
+			want: -1,
+		},
+		{
+			name:  "idle workers",
+			setup: func(pr *PartitionReader) { pr.workers = &partitionWorkers{} },
+			want:  6,
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			r := newTestReader(t, 0)
+			r.initCheckpoint(&memCheckpointer{}, time.Minute)
+			pr := r.readers[0]
+			if tc.setup != nil {
+				tc.setup(pr)
+			}
+			pr.skip(kafka.Message{Partition: 0, Offset: 5})
+			if got := r.handled[0].Load(); got != tc.want {
+				t.Fatalf("progress %d, want %d", got, tc.want)
+			}
+			r.merger, pr.batch, pr.workers = nil, nil, nil
+			r.Close()
+		})
+	}
+}
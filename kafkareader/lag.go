@@ -0,0 +1,87 @@
+package kafkareader
+
+import (
+	"context"
+	"time"
+
+	"github.com/cdpzyafk/go-utils/kafkalib"
+	"github.com/segmentio/kafka-go"
+	"go.uber.org/zap"
+)
+
+const (
+	LAGTIMEOUT = time.Second * 10
+)
+
+// Lag 返回各分区高水位与当前拉取位置的差值，查询失败时记录日志并返回nil
+func (p *Reader) Lag() map[int]int64 {
+	ctx, cancel := context.WithTimeout(p.ctx, LAGTIMEOUT)
+	defer cancel()
+	lag, err := p.LagContext(ctx)
+	if err != nil {
+		p.log.Warn("query lag failed", zap.Error(err))
+		return nil
+	}
+	return lag
+}
+
+// LagContext 同Lag，返回查询错误
+func (p *Reader) LagContext(ctx context.Context) (map[int]int64, error) {
+	ids := make([]int, 0, len(p.readers))
+	for _, pr := range p.readers {
+		ids = append(ids, pr.partition.ID)
+	}
+	offsets, err := kafkalib.ListOffsets(ctx, p.client, p.topic, ids...)
+	if err != nil {
+		return nil, err
+	}
+
+	lag := make(map[int]int64, len(offsets))
+	for _, pr := range p.readers {
+		r, ok := offsets[pr.partition.ID]
+		if !ok {
+			continue
+		}
+		next := pr.next.Load()
+		switch {
+		case next == kafka.FirstOffset:
+			next = r.First
+		case next < 0: // 从最新位置开始且尚未拉取到消息
+			next = r.Last
+		}
+		lag[pr.partition.ID] = max(r.Last-next, 0)
+		p.metrics.Lag(p.topic, pr.partition.ID, lag[pr.partition.ID])
+	}
+	p.lastLag.Store(&lag)
+	return lag, nil
+}
+
+// LastLag 返回最近一次查询到的lag，尚未查询过时返回nil
+func (p *Reader) LastLag() map[int]int64 {
+	if lag := p.lastLag.Load(); lag != nil {
+		return *lag
+	}
+	return nil
+}
+
+// runLagLog 定期查询并记录lag，Close时停止
+func (p *Reader) runLagLog(every time.Duration) {
+	ticker := time.NewTicker(every)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-p.ctx.Done():
+			return
+		case <-ticker.C:
+			lag := p.Lag()
+			if lag == nil {
+				continue
+			}
+			var total int64
+			for _, l := range lag {
+				total += l
+			}
+			p.log.Info("consumer lag", zap.Int64("total", total), zap.Any("partitions", lag))
+		}
+	}
+}
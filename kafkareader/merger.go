@@ -0,0 +1,134 @@
+package kafkareader
+
+import (
+	"container/heap"
+	"time"
+
+	"github.com/segmentio/kafka-go"
+	"go.uber.org/zap"
+)
+
+const (
+	ORDEREDWINDOW      = time.Second
+	ORDEREDMAXBUFFERED = 10000
+)
+
+// OrderedConfig 将所有分区的消息按时间戳归并后由单个goroutine投递
+type OrderedConfig struct {
+	Window      time.Duration // 重排窗口，消息最多被延迟Window后投递, default ORDEREDWINDOW
+	MaxBuffered int           // 缓冲消息数上限，超过后立即投递最早的消息, default ORDEREDMAXBUFFERED
+}
+
+type mergeEntry struct {
+	log     *zap.Logger
+	msg     kafka.Message
+	arrived time.Time
+}
+
+type mergeHeap []mergeEntry
+
+func (h mergeHeap) Len() int { return len(h) }
+func (h mergeHeap) Less(i, j int) bool {
+	if h[i].msg.Time.Equal(h[j].msg.Time) {
+		return h[i].msg.Partition < h[j].msg.Partition
+	}
+	return h[i].msg.Time.Before(h[j].msg.Time)
+}
+func (h mergeHeap) Swap(i, j int) { h[i], h[j] = h[j], h[i] }
+func (h *mergeHeap) Push(x any)   { *h = append(*h, x.(mergeEntry)) }
+func (h *mergeHeap) Pop() any {
+	old := *h
+	n := len(old)
+	e := old[n-1]
+	old[n-1] = mergeEntry{}
+	*h = old[:n-1]
+	return e
+}
+
+type merger struct {
+	in          chan mergeEntry
+	handle      func(*zap.Logger, kafka.Message)
+	window      time.Duration
+	maxBuffered int
+	buffered    mergeHeap
+	maxSeen     time.Time
+	done        chan struct{}
+}
+
+func newMerger(cfg *OrderedConfig, handle func(*zap.Logger, kafka.Message)) *merger {
+	m := &merger{
+		handle:      handle,
+		window:      cfg.Window,
+		maxBuffered: cfg.MaxBuffered,
+		done:        make(chan struct{}),
+	}
+	if m.window <= 0 {
+		m.window = ORDEREDWINDOW
+	}
+	if m.maxBuffered <= 0 {
+		m.maxBuffered = ORDEREDMAXBUFFERED
+	}
+	m.in = make(chan mergeEntry, m.maxBuffered)
+	m.buffered = make(mergeHeap, 0, m.maxBuffered)
+	return m
+}
+
+// push 由各分区goroutine调用，缓冲满时阻塞形成背压
+func (m *merger) push(log *zap.Logger, msg kafka.Message) {
+	m.in <- mergeEntry{log: log, msg: msg, arrived: time.Now()}
+}
+
+// close 在所有分区停止push后调用，投递缓冲中剩余的消息后返回
+func (m *merger) close() {
+	close(m.in)
+	<-m.done
+}
+
+func (m *merger) run() {
+	defer close(m.done)
+	tick := m.window / 4
+	if tick < 10*time.Millisecond {
+		tick = 10 * time.Millisecond
+	}
+	ticker := time.NewTicker(tick)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case e, ok := <-m.in:
+			if !ok {
+				m.drain()
+				return
+			}
+			heap.Push(&m.buffered, e)
+			if e.msg.Time.After(m.maxSeen) {
+				m.maxSeen = e.msg.Time
+			}
+		case <-ticker.C:
+		}
+		m.flush(time.Now())
+	}
+}
+
+// flush 投递已超出重排窗口的消息
+func (m *merger) flush(now time.Time) {
+	horizon := m.maxSeen.Add(-m.window)
+	for len(m.buffered) > 0 {
+		top := m.buffered[0]
+		if len(m.buffered) <= m.maxBuffered &&
+			top.msg.Time.After(horizon) &&
+			now.Sub(top.arrived) < m.window {
+			return
+		}
+		e := heap.Pop(&m.buffered).(mergeEntry)
+		m.handle(e.log, e.msg)
+	}
+}
+
+// drain 按时间顺序投递所有缓冲的消息
+func (m *merger) drain() {
+	for len(m.buffered) > 0 {
+		e := heap.Pop(&m.buffered).(mergeEntry)
+		m.handle(e.log, e.msg)
+	}
+}
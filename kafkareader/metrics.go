@@ -0,0 +1,110 @@
+package kafkareader
+
+import (
+	"strconv"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/segmentio/kafka-go"
+	"go.uber.org/zap"
+)
+
+// MetricsCollector 接收Reader的消费指标，可适配prometheus以外的监控系统
+type MetricsCollector interface {
+	MessageConsumed(topic string, partition int, bytes int)
+	FetchError(topic string, partition int)
+	// HandlerDone 每次调用handler(BatchHandler时为每批)后上报，err为HandlerE重试后的最终错误
+	HandlerDone(topic string, partition int, d time.Duration, err error)
+	// Lag 查询lag时上报，见Reader.Lag和Config.LagLogEvery
+	Lag(topic string, partition int, lag int64)
+}
+
+type nopMetrics struct{}
+
+func (nopMetrics) MessageConsumed(string, int, int)              {}
+func (nopMetrics) FetchError(string, int)                        {}
+func (nopMetrics) HandlerDone(string, int, time.Duration, error) {}
+func (nopMetrics) Lag(string, int, int64)                        {}
+
+// PrometheusMetrics MetricsCollector的prometheus实现，同时实现prometheus.Collector，
+// 需自行通过prometheus.MustRegister导出；多个Reader可共用一个实例
+type PrometheusMetrics struct {
+	messages        *prometheus.CounterVec
+	bytes           *prometheus.CounterVec
+	fetchErrors     *prometheus.CounterVec
+	handlerDuration *prometheus.HistogramVec
+	handlerErrors   *prometheus.CounterVec
+	lag             *prometheus.GaugeVec
+}
+
+func NewPrometheusMetrics(namespace string) *PrometheusMetrics {
+	labels := []string{"topic", "partition"}
+	return &PrometheusMetrics{
+		messages: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace, Name: "kafka_messages_consumed_total", Help: "messages fetched from kafka",
+		}, labels),
+		bytes: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace, Name: "kafka_bytes_consumed_total", Help: "key and value bytes fetched from kafka",
+		}, labels),
+		fetchErrors: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace, Name: "kafka_fetch_errors_total", Help: "fetch errors that caused the partition reader to recover",
+		}, labels),
+		handlerDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: namespace, Name: "kafka_handler_duration_seconds", Help: "handler duration",
+			Buckets: prometheus.ExponentialBuckets(0.0005, 4, 10),
+		}, labels),
+		handlerErrors: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace, Name: "kafka_handler_errors_total", Help: "messages the handler failed to process",
+		}, labels),
+		lag: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Namespace: namespace, Name: "kafka_consumer_lag", Help: "high watermark minus the current fetch offset",
+		}, labels),
+	}
+}
+
+func (m *PrometheusMetrics) MessageConsumed(topic string, partition int, bytes int) {
+	p := strconv.Itoa(partition)
+	m.messages.WithLabelValues(topic, p).Inc()
+	m.bytes.WithLabelValues(topic, p).Add(float64(bytes))
+}
+
+func (m *PrometheusMetrics) FetchError(topic string, partition int) {
+	m.fetchErrors.WithLabelValues(topic, strconv.Itoa(partition)).Inc()
+}
+
+func (m *PrometheusMetrics) HandlerDone(topic string, partition int, d time.Duration, err error) {
+	p := strconv.Itoa(partition)
+	m.handlerDuration.WithLabelValues(topic, p).Observe(d.Seconds())
+	if err != nil {
+		m.handlerErrors.WithLabelValues(topic, p).Inc()
+	}
+}
+
+func (m *PrometheusMetrics) Lag(topic string, partition int, lag int64) {
+	m.lag.WithLabelValues(topic, strconv.Itoa(partition)).Set(float64(lag))
+}
+
+func (m *PrometheusMetrics) collectors() []prometheus.Collector {
+	return []prometheus.Collector{m.messages, m.bytes, m.fetchErrors, m.handlerDuration, m.handlerErrors, m.lag}
+}
+
+func (m *PrometheusMetrics) Describe(ch chan<- *prometheus.Desc) {
+	for _, c := range m.collectors() {
+		c.Describe(ch)
+	}
+}
+
+func (m *PrometheusMetrics) Collect(ch chan<- prometheus.Metric) {
+	for _, c := range m.collectors() {
+		c.Collect(ch)
+	}
+}
+
+// timed 上报Handler的耗时
+func (p *Reader) timed(h func(*zap.Logger, kafka.Message)) func(*zap.Logger, kafka.Message) {
+	return func(log *zap.Logger, msg kafka.Message) {
+		start := time.Now()
+		h(log, msg)
+		p.metrics.HandlerDone(p.topic, msg.Partition, time.Since(start), nil)
+	}
+}
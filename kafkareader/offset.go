@@ -0,0 +1,151 @@
+package kafkareader
+
+import (
+	"context"
+	"time"
+
+	"github.com/cdpzyafk/go-utils/common"
+	"github.com/segmentio/kafka-go"
+)
+
+const (
+	RESOLVEOFFSETTIMEOUT = 10 * time.Second
+)
+
+// StartOffset 分区reader首次创建时的起始位置；recover时从上次拉取到的位置继续
+type StartOffset interface {
+	resolve(ctx context.Context, pr *PartitionReader) (int64, error)
+}
+
+type lastOffset struct{}
+
+// StartAtLast 从最新位置开始，只消费启动后写入的消息(默认)
+func StartAtLast() StartOffset {
+	return lastOffset{}
+}
+
+func (lastOffset) resolve(context.Context, *PartitionReader) (int64, error) {
+	return kafka.LastOffset, nil
+}
+
+type firstOffset struct{}
+
+// StartAtFirst 从最早可用的位置开始，用于全量重放
+func StartAtFirst() StartOffset {
+	return firstOffset{}
+}
+
+func (firstOffset) resolve(context.Context, *PartitionReader) (int64, error) {
+	return kafka.FirstOffset, nil
+}
+
+type explicitOffsets struct {
+	offsets  map[int]int64
+	fallback StartOffset
+}
+
+// StartAtOffsets 按分区指定起始offset，未指定的分区使用fallback(nil时为StartAtLast)；
+// offset超出分区当前可用范围时取最近的边界
+func StartAtOffsets(offsets map[int]int64, fallback StartOffset) StartOffset {
+	if fallback == nil {
+		fallback = StartAtLast()
+	}
+	return explicitOffsets{offsets: offsets, fallback: fallback}
+}
+
+func (e explicitOffsets) resolve(ctx context.Context, pr *PartitionReader) (int64, error) {
+	offset, ok := e.offsets[pr.partition.ID]
+	if !ok {
+		return e.fallback.resolve(ctx, pr)
+	}
+	return pr.clampOffset(ctx, offset)
+}
+
+// OffsetLoader 返回分区上次处理到的下一条offset，没有记录时ok为false
+type OffsetLoader func(ctx context.Context, topic string, partition int) (offset int64, ok bool, err error)
+
+type resumeOffset struct {
+	load     OffsetLoader
+	fallback StartOffset
+}
+
+// ResumeFrom 从load返回的位置继续，没有记录的分区使用fallback(nil时为StartAtLast)；
+// 记录的位置已被清理时从最早可用的位置开始
+func ResumeFrom(load OffsetLoader, fallback StartOffset) StartOffset {
+	if fallback == nil {
+		fallback = StartAtLast()
+	}
+	return resumeOffset{load: load, fallback: fallback}
+}
+
+func (r resumeOffset) resolve(ctx context.Context, pr *PartitionReader) (int64, error) {
+	offset, ok, err := r.load(ctx, pr.parent.topic, pr.partition.ID)
+	if err != nil {
+		return 0, err
+	}
+	if !ok {
+		return r.fallback.resolve(ctx, pr)
+	}
+	return pr.clampOffset(ctx, offset)
+}
+
+type rewindMessages int64
+
+// RewindMessages 每个分区从最新位置回退n条开始，不早于最早可用的位置
+func RewindMessages(n int64) StartOffset {
+	return rewindMessages(n)
+}
+
+func (n rewindMessages) resolve(ctx context.Context, pr *PartitionReader) (int64, error) {
+	first, last, err := pr.readOffsets(ctx)
+	if err != nil {
+		return 0, err
+	}
+	return common.Max(first, last-int64(n)), nil
+}
+
+type rewindDuration time.Duration
+
+// RewindDuration 每个分区从时间戳不早于now-d的第一条消息开始
+func RewindDuration(d time.Duration) StartOffset {
+	return rewindDuration(d)
+}
+
+func (d rewindDuration) resolve(ctx context.Context, pr *PartitionReader) (int64, error) {
+	conn, err := pr.dialLeader(ctx)
+	if err != nil {
+		return 0, err
+	}
+	defer conn.Close()
+	return conn.ReadOffset(time.Now().Add(-time.Duration(d)))
+}
+
+// clampOffset 将offset限制在分区当前可用的[first, last]范围内
+func (pr *PartitionReader) clampOffset(ctx context.Context, offset int64) (int64, error) {
+	first, last, err := pr.readOffsets(ctx)
+	if err != nil {
+		return 0, err
+	}
+	return common.Min(common.Max(offset, first), last), nil
+}
+
+// readOffsets 分区当前最早可用的offset和下一条待写入的offset
+func (pr *PartitionReader) readOffsets(ctx context.Context) (first, last int64, err error) {
+	conn, err := pr.dialLeader(ctx)
+	if err != nil {
+		return 0, 0, err
+	}
+	defer conn.Close()
+	return conn.ReadOffsets()
+}
+
+// dialLeader 依次尝试各broker连接分区leader
+func (pr *PartitionReader) dialLeader(ctx context.Context) (*kafka.Conn, error) {
+	dialer := pr.parent.dialer
+	if dialer == nil {
+		dialer = kafka.DefaultDialer
+	}
+	return common.Fallback(ctx, pr.parent.brokers, 0, func(ctx context.Context, addr string) (*kafka.Conn, error) {
+		return dialer.DialLeader(ctx, "tcp", addr, pr.parent.topic, pr.partition.ID)
+	})
+}
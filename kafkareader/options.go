@@ -0,0 +1,183 @@
+package kafkareader
+
+import (
+	"time"
+
+	"github.com/cdpzyafk/go-utils/common"
+	"github.com/cdpzyafk/go-utils/kafkalib"
+	"github.com/cdpzyafk/go-utils/optkit"
+	"github.com/segmentio/kafka-go"
+	"go.uber.org/zap"
+)
+
+type ConfigOption = optkit.Option[Config]
+
+// NewConfig 以函数选项构造Config，未设置的字段在CreateReader中取默认值
+func NewConfig(topic string, handler func(*zap.Logger, kafka.Message), opts ...ConfigOption) (*Config, error) {
+	cfg, err := optkit.Apply(Config{Topic: topic, Handler: handler}, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return &cfg, nil
+}
+
+// Validate 校验必填字段，profile中的brokers在CreateReader中解析
+func (cfg *Config) Validate() error {
+	if len(cfg.Brokers) == 0 && cfg.Profile == "" {
+		return ErrNoBrokers
+	}
+	if cfg.Topic == "" {
+		return ErrNoTopic
+	}
+	handlers := 0
+	for _, set := range []bool{cfg.Handler != nil, cfg.HandlerE != nil, cfg.BatchHandler != nil} {
+		if set {
+			handlers++
+		}
+	}
+	if handlers == 0 {
+		return ErrNoHandler
+	}
+	if handlers > 1 {
+		return ErrHandlerConflict
+	}
+	if cfg.BatchHandler != nil && cfg.Ordered != nil {
+		return ErrBatchOrdered
+	}
+	if (cfg.Concurrency > 1 || cfg.BufferSize > 0) && (cfg.BatchHandler != nil || cfg.Ordered != nil) {
+		return ErrConcurrency
+	}
+	return nil
+}
+
+func WithName(name string) ConfigOption {
+	return func(cfg *Config) {
+		cfg.Name = name
+	}
+}
+
+func WithBrokers(brokers ...string) ConfigOption {
+	return func(cfg *Config) {
+		cfg.Brokers = brokers
+	}
+}
+
+// WithProfile 使用kafkalib中注册的集群profile
+func WithProfile(profile string) ConfigOption {
+	return func(cfg *Config) {
+		cfg.Profile = profile
+	}
+}
+
+// WithAuth 连接broker时使用TLS和/或SASL，不需要的传nil
+func WithAuth(tlsCfg *kafkalib.TLSConfig, saslCfg *kafkalib.SASLConfig) ConfigOption {
+	return func(cfg *Config) {
+		cfg.TLS, cfg.SASL = tlsCfg, saslCfg
+	}
+}
+
+func WithFetchSizes(minBytes, maxBytes int) ConfigOption {
+	return func(cfg *Config) {
+		cfg.MinBytes, cfg.MaxBytes = minBytes, maxBytes
+	}
+}
+
+func WithReadBackoffMin(d time.Duration) ConfigOption {
+	return func(cfg *Config) {
+		cfg.ReadBackoffMin = d
+	}
+}
+
+func WithStartOffset(offset StartOffset) ConfigOption {
+	return func(cfg *Config) {
+		cfg.StartOffset = offset
+	}
+}
+
+// WithBatchHandler 按批投递，代替NewConfig中的handler(需传nil)；size、wait<=0时使用默认值
+func WithBatchHandler(handler func(*zap.Logger, []kafka.Message), size int, wait time.Duration) ConfigOption {
+	return func(cfg *Config) {
+		cfg.BatchHandler, cfg.MaxBatchSize, cfg.MaxBatchWait = handler, size, wait
+	}
+}
+
+// WithHandlerE 使用返回错误的handler，代替NewConfig中的handler(需传nil)，失败的消息写入dlq
+func WithHandlerE(handler func(*zap.Logger, kafka.Message) error, dlq DeadLetter) ConfigOption {
+	return func(cfg *Config) {
+		cfg.HandlerE, cfg.DeadLetter = handler, dlq
+	}
+}
+
+// WithRetry HandlerE失败后按policy重试
+func WithRetry(policy RetryPolicy) ConfigOption {
+	return func(cfg *Config) {
+		cfg.Retry = &policy
+	}
+}
+
+// WithMetrics 上报消费指标，lagEvery>0时定期查询lag
+func WithMetrics(metrics MetricsCollector, lagEvery time.Duration) ConfigOption {
+	return func(cfg *Config) {
+		cfg.Metrics, cfg.LagLogEvery = metrics, lagEvery
+	}
+}
+
+// WithFilter 只处理filter返回true的消息，多个条件用AllOf/AnyOf组合
+func WithFilter(filter Filter) ConfigOption {
+	return func(cfg *Config) {
+		cfg.Filter = filter
+	}
+}
+
+// WithBuffer 每个分区在拉取与处理之间最多缓冲size条消息
+func WithBuffer(size int) ConfigOption {
+	return func(cfg *Config) {
+		cfg.BufferSize = size
+	}
+}
+
+// WithRateLimit 每秒最多处理perSecond条消息，perReader时所有分区共用该速率
+func WithRateLimit(perSecond float64, perReader bool) ConfigOption {
+	return func(cfg *Config) {
+		cfg.MaxMessagesPerSecond, cfg.RatePerReader = perSecond, perReader
+	}
+}
+
+// WithConcurrency 每个分区n个worker并发处理，keyOrdered时同一key保持顺序
+func WithConcurrency(n int, keyOrdered bool) ConfigOption {
+	return func(cfg *Config) {
+		cfg.Concurrency, cfg.KeyOrdered = n, keyOrdered
+	}
+}
+
+// WithCheckpointer 定期将处理进度保存到cp，every<=0时使用CHECKPOINTEVERY
+func WithCheckpointer(cp Checkpointer, every time.Duration) ConfigOption {
+	return func(cfg *Config) {
+		cfg.Checkpointer, cfg.CheckpointEvery = cp, every
+	}
+}
+
+// WithBreaker partitions为空表示影响全部分区
+func WithBreaker(breaker Breaker, partitions ...int) ConfigOption {
+	return func(cfg *Config) {
+		cfg.Breaker, cfg.BreakerPartitions = breaker, partitions
+	}
+}
+
+func WithWatermark(wm WatermarkConfig) ConfigOption {
+	return func(cfg *Config) {
+		cfg.Watermark = &wm
+	}
+}
+
+func WithOrdered(ordered OrderedConfig) ConfigOption {
+	return func(cfg *Config) {
+		cfg.Ordered = &ordered
+	}
+}
+
+func WithHeartbeat(h *common.Heartbeat) ConfigOption {
+	return func(cfg *Config) {
+		cfg.Heartbeat = h
+	}
+}
@@ -2,8 +2,13 @@ package kafkareader
 
 import (
 	"context"
+	"errors"
+	"strconv"
+	"sync"
+	"sync/atomic"
 	"time"
 
+	"github.com/cdpzyafk/go-utils/common"
 	"github.com/segmentio/kafka-go"
 	"go.uber.org/zap"
 )
@@ -13,70 +18,218 @@ type PartitionReader struct {
 	log       *zap.Logger
 	reader    *kafka.Reader
 	partition kafka.Partition
-	stopCh    chan struct{}
+	ctx       context.Context // Stop时取消，结束拉取循环
+	cancel    context.CancelFunc
+	breaker   *partitionBreaker
+	next      atomic.Int64      // 下一条待拉取的offset，负数表示尚未确定或为kafka.FirstOffset/LastOffset
+	retune    atomic.Bool       // 拉取参数已变更，下一轮循环重建reader
+	rework    atomic.Bool       // Concurrency已变更，下一轮循环重建worker
+	backoff   *common.Backoff   // 连续出错时重建reader的退避，拉取成功后重置
+	batch     *batch            // 设置BatchHandler时累积消息
+	workers   *partitionWorkers // 设置BufferSize或Concurrency>1时处理消息
+	pauseMu   sync.Mutex
+	resumed   chan struct{} // 非nil表示已暂停，Resume时关闭
+
+	heartbeatName string
 }
 
 func (pr *PartitionReader) Start() {
-	ctx := context.Background()
+	defer pr.closeReader()
+	defer pr.flushBatch()
+	pr.restartWorkers()
+	defer pr.stopWorkers()
 
-	maxOffset := int64(0)
+	maxOffset := int64(-1)
 
-	for {
-		if msg, err := pr.reader.FetchMessage(ctx); err == nil {
+	for pr.ctx.Err() == nil {
+		if !pr.waitBreaker() || !pr.waitResume() {
+			return
+		}
+		if pr.rework.CompareAndSwap(true, false) {
+			pr.log.Info("concurrency changed, restart workers")
+			pr.restartWorkers()
+		}
+		if pr.retune.CompareAndSwap(true, false) {
+			pr.log.Info("fetch tuning changed, recreate reader")
+			if !pr.recover() {
+				return
+			}
+		}
+		pr.parent.heartbeat.Beat(pr.heartbeatName)
+		// 限时拉取，topic空闲时也能定期报告心跳
+		fetchCtx, cancel := context.WithTimeout(pr.ctx, pr.fetchTimeout())
+		msg, err := pr.reader.FetchMessage(fetchCtx)
+		cancel()
+		if pr.ctx.Err() != nil {
+			return
+		}
+		if errors.Is(err, context.DeadlineExceeded) {
+			pr.flushDueBatch()
+			continue
+		}
+		if err == nil {
+			pr.backoff.Reset()
+			pr.parent.metrics.MessageConsumed(pr.parent.topic, pr.partition.ID, len(msg.Key)+len(msg.Value))
 			if msg.Offset <= maxOffset {
 				continue
 			}
 			maxOffset = msg.Offset
-			pr.parent.handleEvent(pr.log, msg)
+			pr.next.Store(msg.Offset + 1)
+			if filter := pr.parent.filter; filter != nil && !filter(msg) || pr.duplicate(msg) {
+				pr.skip(msg)
+				continue
+			}
+			if !pr.waitRate() {
+				return
+			}
+			if wm := pr.parent.watermark; wm != nil {
+				if wm.wait(pr.ctx, pr.partition.ID, msg.Time) {
+					pr.log.Debug("watermark wait timeout", zap.Time("time", msg.Time))
+				}
+				if pr.ctx.Err() != nil {
+					return
+				}
+			}
+			if pr.batch != nil {
+				pr.addToBatch(msg)
+				pr.flushDueBatch()
+			} else if pr.workers != nil {
+				pr.submit(msg)
+			} else {
+				pr.parent.dispatch(pr.log, msg)
+			}
 		} else {
-			time.Sleep(time.Millisecond * 200)
-			pr.log.Error("reader broken, start to recover...", zap.Error(err))
-			pr.recover()
+			pr.parent.metrics.FetchError(pr.parent.topic, pr.partition.ID)
+			wait := pr.backoff.Next()
+			pr.log.Error("reader broken, start to recover...", zap.Error(err), zap.Duration("wait", wait))
+			if !pr.sleep(wait) || !pr.recover() {
+				return
+			}
 		}
 	}
 }
 
+// Stop 结束拉取循环，正在处理的消息处理完后Start返回
 func (pr *PartitionReader) Stop() {
-	// TODO 支持正确关闭
+	pr.cancel()
+}
+
+// recover 重建reader直到成功，Stop后返回false
+func (pr *PartitionReader) recover() bool {
+	pr.closeReader()
+
+	for {
+		err := pr.createReader()
+		if err == nil {
+			return true
+		}
+		wait := pr.backoff.Next()
+		pr.log.Error("recover failed", zap.Error(err), zap.Duration("wait", wait))
+		if !pr.sleep(wait) {
+			return false
+		}
+	}
 }
 
-func (pr *PartitionReader) recover() {
+func (pr *PartitionReader) closeReader() {
 	if pr.reader != nil {
-		pr.reader.Close()
+		if err := pr.reader.Close(); err != nil {
+			pr.log.Warn("close reader failed", zap.Error(err))
+		}
+		pr.reader = nil
+	}
+}
+
+// sleep 等待d，期间Stop时返回false
+func (pr *PartitionReader) sleep(d time.Duration) bool {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-pr.ctx.Done():
+		return false
+	case <-timer.C:
+		return true
 	}
+}
 
+// delay 在handler中等待d，期间继续报告分区心跳，Close时返回false
+func (p *Reader) delay(partition int, d time.Duration) bool {
+	var heartbeatName string
+	if pr, err := p.partitionReader(partition); err == nil {
+		heartbeatName = pr.heartbeatName
+	}
+	deadline := time.Now().Add(d)
 	for {
-		if err := pr.createReader(); err != nil {
-			pr.log.Error("recover failed", zap.Error(err))
-			time.Sleep(time.Second * 3)
-			continue
+		wait := time.Until(deadline)
+		if wait <= 0 {
+			return true
+		}
+		timer := time.NewTimer(min(wait, HEARTBEATEVERY))
+		select {
+		case <-p.ctx.Done():
+			timer.Stop()
+			return false
+		case <-timer.C:
+		}
+		if heartbeatName != "" {
+			p.heartbeat.Beat(heartbeatName)
 		}
-		break
 	}
 }
 
 func (pr *PartitionReader) createReader() error {
+	minBytes, maxBytes, readBackoffMin := pr.parent.fetchTuning()
 	pr.reader = kafka.NewReader(kafka.ReaderConfig{
 		Brokers:        pr.parent.brokers,
+		Dialer:         pr.parent.dialer,
 		Topic:          pr.parent.topic,
 		Partition:      pr.partition.ID,
-		MinBytes:       pr.parent.minBytes,
-		MaxBytes:       pr.parent.maxBytes,
-		ReadBackoffMin: pr.parent.readBackoffMin,
+		MinBytes:       minBytes,
+		MaxBytes:       maxBytes,
+		ReadBackoffMin: readBackoffMin,
 	})
-	err := pr.reader.SetOffset(kafka.LastOffset)
-	return err
+
+	offset := pr.next.Load()
+	if offset < 0 {
+		ctx, cancel := context.WithTimeout(context.Background(), RESOLVEOFFSETTIMEOUT)
+		defer cancel()
+		var err error
+		if offset, err = pr.parent.startOffset.resolve(ctx, pr); err != nil {
+			return err
+		}
+		pr.next.Store(offset)
+	}
+	return pr.reader.SetOffset(offset)
 }
 
 func NewPartitionReader(reader *Reader, partition kafka.Partition) (*PartitionReader, error) {
+	ctx, cancel := context.WithCancel(reader.ctx)
 	pr := &PartitionReader{
+		ctx:       ctx,
+		cancel:    cancel,
 		parent:    reader,
 		partition: partition,
-		stopCh:    make(chan struct{}, 1),
+		backoff:   common.NewBackoff(RECOVERMIN, RECOVERMAX).WithJitter(common.JitterEqual, nil),
 		log:       reader.log.With(zap.Int("partition", partition.ID)),
-	}
 
-	err := pr.createReader()
+		heartbeatName: reader.heartbeatName + "/" + strconv.Itoa(partition.ID),
+	}
+	pr.next.Store(-1)
+	if reader.batchHandler != nil {
+		pr.batch = newBatch(reader.maxBatchSize, reader.maxBatchWait)
+	}
+	if reader.breaker != nil && breakerAffects(reader.breakerPartitions, partition.ID) {
+		pr.breaker = &partitionBreaker{
+			breaker: reader.breaker,
+			poll:    reader.breakerPoll,
+			state:   BreakerClosed,
+		}
+	}
 
-	return pr, err
+	if err := pr.createReader(); err != nil {
+		cancel()
+		pr.closeReader()
+		return nil, err
+	}
+	return pr, nil
 }
@@ -0,0 +1,108 @@
+package kafkareader
+
+import (
+	"fmt"
+	"time"
+)
+
+// Pause 暂停分区拉取，保留连接和已拉取到的位置，当前消息处理完后生效
+func (p *Reader) Pause(partition int) error {
+	pr, err := p.partitionReader(partition)
+	if err != nil {
+		return err
+	}
+	pr.pause()
+	return nil
+}
+
+// Resume 恢复分区拉取
+func (p *Reader) Resume(partition int) error {
+	pr, err := p.partitionReader(partition)
+	if err != nil {
+		return err
+	}
+	pr.resume()
+	return nil
+}
+
+func (p *Reader) PauseAll() {
+	for _, pr := range p.readers {
+		pr.pause()
+	}
+}
+
+func (p *Reader) ResumeAll() {
+	for _, pr := range p.readers {
+		pr.resume()
+	}
+}
+
+// Paused 返回当前暂停的分区
+func (p *Reader) Paused() []int {
+	var paused []int
+	for _, pr := range p.readers {
+		pr.pauseMu.Lock()
+		if pr.resumed != nil {
+			paused = append(paused, pr.partition.ID)
+		}
+		pr.pauseMu.Unlock()
+	}
+	return paused
+}
+
+func (p *Reader) partitionReader(partition int) (*PartitionReader, error) {
+	for _, pr := range p.readers {
+		if pr.partition.ID == partition {
+			return pr, nil
+		}
+	}
+	return nil, fmt.Errorf("%w: %d", ErrUnknownPartition, partition)
+}
+
+func (pr *PartitionReader) pause() {
+	pr.pauseMu.Lock()
+	defer pr.pauseMu.Unlock()
+	if pr.resumed == nil {
+		pr.resumed = make(chan struct{})
+		if wm := pr.parent.watermark; wm != nil {
+			wm.setPaused(pr.partition.ID, true)
+		}
+		pr.log.Info("partition paused")
+	}
+}
+
+func (pr *PartitionReader) resume() {
+	pr.pauseMu.Lock()
+	defer pr.pauseMu.Unlock()
+	if pr.resumed != nil {
+		close(pr.resumed)
+		pr.resumed = nil
+		if wm := pr.parent.watermark; wm != nil {
+			wm.setPaused(pr.partition.ID, false)
+		}
+		pr.log.Info("partition resumed")
+	}
+}
+
+// waitResume 暂停期间阻塞并继续报告心跳，等待期间Stop时返回false
+func (pr *PartitionReader) waitResume() bool {
+	for {
+		pr.pauseMu.Lock()
+		resumed := pr.resumed
+		pr.pauseMu.Unlock()
+		if resumed == nil {
+			return true
+		}
+
+		pr.parent.heartbeat.Beat(pr.heartbeatName)
+		timer := time.NewTimer(HEARTBEATEVERY)
+		select {
+		case <-pr.ctx.Done():
+			timer.Stop()
+			return false
+		case <-resumed:
+		case <-timer.C:
+		}
+		timer.Stop()
+	}
+}
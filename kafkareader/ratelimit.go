@@ -0,0 +1,36 @@
+package kafkareader
+
+import (
+	"math"
+
+	"github.com/cdpzyafk/go-utils/common"
+)
+
+// rateLimiter 限制处理速率，按分区或整个Reader共用一个桶
+type rateLimiter struct {
+	bucket    *common.TokenBucket[int]
+	perReader bool
+}
+
+func newRateLimiter(perSecond float64, burst int, perReader bool) *rateLimiter {
+	if burst <= 0 {
+		burst = int(math.Ceil(perSecond))
+	}
+	return &rateLimiter{
+		bucket:    common.NewTokenBucket[int](2, perSecond, max(burst, 1)),
+		perReader: perReader,
+	}
+}
+
+// waitRate 等待处理配额，等待期间Stop时返回false
+func (pr *PartitionReader) waitRate() bool {
+	rl := pr.parent.rateLimiter.Load()
+	if rl == nil {
+		return true
+	}
+	key := pr.partition.ID
+	if rl.perReader {
+		key = -1
+	}
+	return rl.bucket.Wait(pr.ctx, key) == nil
+}
@@ -1,8 +1,14 @@
 package kafkareader
 
 import (
+	"context"
+	"fmt"
+	"strconv"
+	"sync"
+	"sync/atomic"
 	"time"
 
+	"github.com/cdpzyafk/go-utils/common"
 	"github.com/cdpzyafk/go-utils/jsonize"
 	"github.com/cdpzyafk/go-utils/kafkalib"
 	"github.com/cdpzyafk/go-utils/logutil"
@@ -12,30 +18,98 @@ import (
 
 var (
 	log = logutil.GetLogger().With(zap.String("pkg", "kfreader"))
+
+	readerSeq      atomic.Int64
+	heartbeatNames sync.Map // 使用中的心跳名，未关闭的Reader之间不能重复
 )
 
+type heartbeatKey struct {
+	heartbeat *common.Heartbeat
+	name      string
+}
+
 type Reader struct {
 	handleEvent        func(*zap.Logger, kafka.Message)
+	batchHandler       func(*zap.Logger, []kafka.Message)
+	dlq                DeadLetter
+	filter             Filter
+	dedupe             *common.DedupeStore
+	dedupeKey          DedupeKey
+	rateLimiter        atomic.Pointer[rateLimiter]
+	maxPerSecond       float64
+	rateBurst          int
+	ratePerReader      bool
+	retry              *RetryPolicy
+	maxBatchSize       int
+	maxBatchWait       time.Duration
+	concurrency        int // 由tuneMu保护
+	keyOrdered         bool
+	bufferSize         int
 	log                *zap.Logger
 	topic              string
 	brokers            []string
+	dialer             *kafka.Dialer
+	client             *kafka.Client    // 查询lag等元数据
+	transport          *kafka.Transport // client使用，Close时释放后台刷新和空闲连接
+	tuneMu             sync.RWMutex
 	minBytes, maxBytes int
 	readers            []*PartitionReader
 	partitions         []kafka.Partition
-	status             bool
-	closing            bool
+	ctx                context.Context // Close时取消，所有分区的拉取循环随之结束
+	cancel             context.CancelFunc
+	wg                 sync.WaitGroup
+	closeOnce          sync.Once
+	started            atomic.Bool
 	readBackoffMin     time.Duration
+	tuningChanges      atomic.Int64
+	startOffset        StartOffset
+
+	breaker           Breaker
+	breakerPartitions []int
+	breakerPoll       time.Duration
+	onBreakerEvent    func(BreakerEvent)
+
+	watermark *watermark
+	merger    *merger
+
+	heartbeat     *common.Heartbeat
+	heartbeatName string
+
+	lagLogEvery time.Duration
+	metrics     MetricsCollector
+	lastLag     atomic.Pointer[map[int]int64]
+
+	checkpointer    Checkpointer
+	checkpointEvery time.Duration
+	checkpointMu    sync.Mutex
+	checkpointDone  chan struct{}
+	handled         map[int]*atomic.Int64 // 各分区下一条待处理的offset，-1表示尚未处理
+	held            map[int]*atomic.Int64 // 各分区最早的未能处理的offset，保存的进度不越过它，-1表示没有
+	saved           map[int]int64         // 各分区上次保存的offset
 }
 
 func CreateReader(cfg *Config) (*Reader, error) {
-	if len(cfg.Brokers) == 0 {
-		return nil, ErrNoBrokers
+	var dialer *kafka.Dialer
+	if cfg.Profile != "" {
+		profile, err := kafkalib.GetProfile(cfg.Profile)
+		if err != nil {
+			return nil, err
+		}
+		if len(cfg.Brokers) == 0 {
+			cfg.Brokers = profile.Brokers
+		}
+		if dialer, err = profile.Dialer(); err != nil {
+			return nil, err
+		}
 	}
-	if cfg.Topic == "" {
-		return nil, ErrNoTopic
+	if cfg.TLS != nil || cfg.SASL != nil {
+		var err error
+		if dialer, err = kafkalib.NewDialer(cfg.TLS, cfg.SASL); err != nil {
+			return nil, err
+		}
 	}
-	if cfg.Handler == nil {
-		return nil, ErrNoHandler
+	if err := cfg.Validate(); err != nil {
+		return nil, err
 	}
 	if cfg.MinBytes <= 0 {
 		cfg.MinBytes = MINBYTES
@@ -49,13 +123,38 @@ func CreateReader(cfg *Config) (*Reader, error) {
 	if cfg.ReadBackoffMin <= READBACKOFFMIN {
 		cfg.ReadBackoffMin = READBACKOFFMIN
 	}
+	if cfg.StartOffset == nil {
+		if cfg.Checkpointer != nil {
+			cfg.StartOffset = ResumeFrom(cfg.Checkpointer.Load, StartAtLast())
+		} else {
+			cfg.StartOffset = StartAtLast()
+		}
+	}
+	if cfg.CheckpointEvery <= 0 {
+		cfg.CheckpointEvery = CHECKPOINTEVERY
+	}
+	if cfg.BufferSize <= 0 && cfg.Concurrency > 1 {
+		cfg.BufferSize = WORKERQUEUE
+	}
+	if cfg.MaxBatchSize <= 0 {
+		cfg.MaxBatchSize = MAXBATCHSIZE
+	}
+	if cfg.MaxBatchWait <= 0 {
+		cfg.MaxBatchWait = MAXBATCHWAIT
+	}
+	if cfg.DedupeKey == nil {
+		cfg.DedupeKey = MessageKey
+	}
+	if cfg.BreakerPoll <= 0 {
+		cfg.BreakerPoll = BREAKERPOLL
+	}
 
 	log := log
 	if cfg.Name != "" {
 		log = log.With(zap.String("name", cfg.Name))
 	}
 
-	partitions, err := kafkalib.LookupPartitions(log, cfg.Brokers, cfg.Topic)
+	partitions, err := kafkalib.LookupPartitionsWithDialer(log, dialer, cfg.Brokers, cfg.Topic)
 	if err != nil {
 		log.Error("failed look partions",
 			zap.Error(err),
@@ -64,16 +163,74 @@ func CreateReader(cfg *Config) (*Reader, error) {
 		return nil, err
 	}
 
+	ctx, cancel := context.WithCancel(context.Background())
 	r := &Reader{
+		ctx:            ctx,
+		cancel:         cancel,
 		log:            log,
 		topic:          cfg.Topic,
 		brokers:        cfg.Brokers,
+		dialer:         dialer,
 		handleEvent:    cfg.Handler,
+		batchHandler:   cfg.BatchHandler,
+		dlq:            cfg.DeadLetter,
+		filter:         cfg.Filter,
+		dedupe:         cfg.Dedupe,
+		dedupeKey:      cfg.DedupeKey,
+		maxBatchSize:   cfg.MaxBatchSize,
+		maxBatchWait:   cfg.MaxBatchWait,
+		concurrency:    cfg.Concurrency,
+		keyOrdered:     cfg.KeyOrdered,
+		bufferSize:     cfg.BufferSize,
 		partitions:     partitions,
 		minBytes:       cfg.MinBytes,
 		maxBytes:       cfg.MaxBytes,
 		readBackoffMin: cfg.ReadBackoffMin,
+		startOffset:    cfg.StartOffset,
 		readers:        make([]*PartitionReader, 0, len(partitions)),
+
+		breaker:           cfg.Breaker,
+		breakerPartitions: cfg.BreakerPartitions,
+		breakerPoll:       cfg.BreakerPoll,
+		onBreakerEvent:    cfg.OnBreakerEvent,
+
+		heartbeat: cfg.Heartbeat,
+
+		lagLogEvery: cfg.LagLogEvery,
+		metrics:     cfg.Metrics,
+	}
+	r.maxPerSecond, r.rateBurst, r.ratePerReader = cfg.MaxMessagesPerSecond, cfg.RateBurst, cfg.RatePerReader
+	r.transport = kafkalib.NewTransport(dialer)
+	r.client = &kafka.Client{Addr: kafka.TCP(cfg.Brokers...), Transport: r.transport}
+	if cfg.MaxMessagesPerSecond > 0 {
+		r.rateLimiter.Store(newRateLimiter(cfg.MaxMessagesPerSecond, cfg.RateBurst, cfg.RatePerReader))
+	}
+	if cfg.Retry != nil {
+		r.retry = cfg.Retry.withDefaults()
+	}
+	if r.metrics == nil {
+		r.metrics = nopMetrics{}
+	} else if cfg.Handler != nil {
+		r.handleEvent = r.timed(cfg.Handler)
+	}
+	if cfg.HandlerE != nil {
+		r.handleEvent = r.fallible(cfg.HandlerE)
+	}
+	if r.heartbeat == nil {
+		r.heartbeat = common.DefaultHeartbeat
+	}
+	if err := r.claimHeartbeatName(cfg.Name, cfg.Topic); err != nil {
+		cancel()
+		return nil, err
+	}
+	if cfg.Checkpointer != nil {
+		r.initCheckpoint(cfg.Checkpointer, cfg.CheckpointEvery)
+	}
+	if cfg.Watermark != nil {
+		r.watermark = newWatermark(cfg.Watermark, partitions)
+	}
+	if cfg.Ordered != nil {
+		r.merger = newMerger(cfg.Ordered, r.handle)
 	}
 
 	for i := 0; i < len(partitions); i++ {
@@ -84,6 +241,7 @@ func CreateReader(cfg *Config) (*Reader, error) {
 				zap.Error(err),
 				zap.Int("id", partition.ID),
 				zap.String("topic", cfg.Topic))
+			r.Close()
 			return nil, err
 		}
 		r.readers = append(r.readers, reader)
@@ -95,15 +253,93 @@ func CreateReader(cfg *Config) (*Reader, error) {
 }
 
 func (p *Reader) Start() {
+	p.started.Store(true)
+	if p.merger != nil {
+		go p.merger.run()
+	}
+	if p.checkpointer != nil {
+		go p.runCheckpoint(p.checkpointEvery)
+	}
+	if p.lagLogEvery > 0 {
+		go p.runLagLog(p.lagLogEvery)
+	}
 	for _, reader := range p.readers {
-		go reader.Start()
+		p.wg.Add(1)
+		go func() {
+			defer p.wg.Done()
+			reader.Start()
+		}()
 	}
-	p.status = true
 }
 
-func (p *Reader) Close() {
-	p.closing = true
+// claimHeartbeatName 未设置Name的Reader以"<Topic>#<序号>"区分，同一Heartbeat上Name重复时返回ErrDuplicateName
+func (p *Reader) claimHeartbeatName(name, topic string) error {
+	if name == "" {
+		name = topic + "#" + strconv.FormatInt(readerSeq.Add(1), 10)
+	}
+	if _, loaded := heartbeatNames.LoadOrStore(heartbeatKey{p.heartbeat, name}, p); loaded {
+		return fmt.Errorf("%w: %s", ErrDuplicateName, name)
+	}
+	p.heartbeatName = name
+	return nil
+}
+
+// LastBeat 返回各分区拉取循环中最早的一次心跳时间，任一分区未心跳过时返回零值
+func (p *Reader) LastBeat() time.Time {
+	var oldest time.Time
 	for _, reader := range p.readers {
-		reader.Stop()
+		t, ok := p.heartbeat.LastBeat(reader.heartbeatName)
+		if !ok {
+			return time.Time{}
+		}
+		if oldest.IsZero() || t.Before(oldest) {
+			oldest = t
+		}
+	}
+	return oldest
+}
+
+// dispatch 将分区拉取到的消息交给handler或归并器
+func (p *Reader) dispatch(log *zap.Logger, msg kafka.Message) {
+	if p.merger != nil {
+		p.merger.push(log, msg)
+		return
 	}
+	p.handle(log, msg)
+}
+
+func (p *Reader) handle(log *zap.Logger, msg kafka.Message) {
+	p.handleEvent(log, msg)
+	p.markHandled(msg)
+}
+
+// Close 停止所有分区的拉取并关闭底层reader，阻塞到各分区goroutine退出、
+// 已拉取的消息处理完(Ordered时包括归并缓冲中的消息)，并移除各分区的心跳；可重复调用
+func (p *Reader) Close() {
+	p.closeOnce.Do(func() {
+		p.cancel()
+		for _, reader := range p.readers {
+			reader.Stop()
+		}
+		p.wg.Wait()
+		// 未Start或Start前创建失败的分区，底层reader不会被拉取循环关闭
+		for _, reader := range p.readers {
+			reader.closeReader()
+			p.heartbeat.Remove(reader.heartbeatName)
+		}
+		heartbeatNames.CompareAndDelete(heartbeatKey{p.heartbeat, p.heartbeatName}, p)
+		if p.transport != nil {
+			p.transport.CloseIdleConnections()
+		}
+		if p.merger != nil && p.started.Load() {
+			p.merger.close()
+		}
+		if p.checkpointer != nil {
+			if p.started.Load() {
+				<-p.checkpointDone
+			}
+			p.saveCheckpoint()
+		}
+		p.log.Info("reader closed")
+	})
 }
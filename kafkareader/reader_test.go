@@ -0,0 +1,154 @@
+package kafkareader
+
+import (
+	"context"
+	"errors"
+	"maps"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/cdpzyafk/go-utils/common"
+	"github.com/segmentio/kafka-go"
+	"go.uber.org/zap"
+)
+
+// newTestReader 不连接broker的Reader，kafka reader在首次拉取时才会拨号
+func newTestReader(t *testing.T, partitions ...int) *Reader {
+	t.Helper()
+	ctx, cancel := context.WithCancel(context.Background())
+	r := &Reader{
+		ctx:            ctx,
+		cancel:         cancel,
+		log:            zap.NewNop(),
+		topic:          "test",
+		brokers:        []string{"127.0.0.1:1"},
+		handleEvent:    func(*zap.Logger, kafka.Message) {},
+		minBytes:       MINBYTES,
+		maxBytes:       MAXBYTES,
+		readBackoffMin: READBACKOFFMIN,
+		startOffset:    StartAtLast(),
+		heartbeat:      common.NewHeartbeat(""),
+		heartbeatName:  "test",
+		metrics:        nopMetrics{},
+	}
+	for _, id := range partitions {
+		r.partitions = append(r.partitions, kafka.Partition{Topic: r.topic, ID: id})
+		pr, err := NewPartitionReader(r, kafka.Partition{Topic: r.topic, ID: id})
+		if err != nil {
+			t.Fatal(err)
+		}
+		r.readers = append(r.readers, pr)
+	}
+	return r
+}
+
+// memCheckpointer 保存在内存中的Checkpointer
+type memCheckpointer struct {
+	mu      sync.Mutex
+	offsets map[int]int64
+	saves   []map[int]int64
+}
+
+func (mc *memCheckpointer) Load(_ context.Context, _ string, partition int) (int64, bool, error) {
+	mc.mu.Lock()
+	defer mc.mu.Unlock()
+	offset, ok := mc.offsets[partition]
+	return offset, ok, nil
+}
+
+func (mc *memCheckpointer) Save(_ context.Context, _ string, offsets map[int]int64) error {
+	mc.mu.Lock()
+	defer mc.mu.Unlock()
+	if mc.offsets == nil {
+		mc.offsets = make(map[int]int64)
+	}
+	for partition, offset := range offsets {
+		mc.offsets[partition] = offset
+	}
+	mc.saves = append(mc.saves, maps.Clone(offsets))
+	return nil
+}
+
+func TestReaderCloseWithoutStart(t *testing.T) {
+	r := newTestReader(t, 0, 1)
+	r.Close()
+	for _, pr := range r.readers {
+		if pr.reader != nil {
+			t.Fatalf("partition %d reader not closed", pr.partition.ID)
+		}
+	}
+	r.Close()
+}
+
+func TestReaderCloseAfterStart(t *testing.T) {
+	r := newTestReader(t, 0, 1)
+	r.Start()
+	deadline := time.Now().Add(time.Second)
+	for r.LastBeat().IsZero() {
+		if time.Now().After(deadline) {
+			t.Fatal("partition loops did not beat")
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+
+	done := make(chan struct{})
+	go func() {
+		r.Close()
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("Close did not return")
+	}
+	for _, pr := range r.readers {
+		if pr.reader != nil {
+			t.Fatalf("partition %d reader not closed", pr.partition.ID)
+		}
+		if _, ok := r.heartbeat.LastBeat(pr.heartbeatName); ok {
+			t.Fatalf("heartbeat %s not removed", pr.heartbeatName)
+		}
+	}
+	if stale := r.heartbeat.Stale(0); len(stale) != 0 {
+		t.Fatalf("stale heartbeats after close %v", stale)
+	}
+}
+
+func TestClaimHeartbeatName(t *testing.T) {
+	hb := common.NewHeartbeat("")
+	newReader := func() *Reader {
+		ctx, cancel := context.WithCancel(context.Background())
+		return &Reader{ctx: ctx, cancel: cancel, log: zap.NewNop(), heartbeat: hb}
+	}
+
+	// 未命名的Reader即使topic相同也不共用心跳
+	a, b := newReader(), newReader()
+	if err := a.claimHeartbeatName("", "orders"); err != nil {
+		t.Fatal(err)
+	}
+	if err := b.claimHeartbeatName("", "orders"); err != nil {
+		t.Fatal(err)
+	}
+	if a.heartbeatName == b.heartbeatName {
+		t.Fatalf("unnamed readers share heartbeat name %s", a.heartbeatName)
+	}
+
+	named := newReader()
+	if err := named.claimHeartbeatName("billing", "orders"); err != nil {
+		t.Fatal(err)
+	}
+	if err := newReader().claimHeartbeatName("billing", "payments"); !errors.Is(err, ErrDuplicateName) {
+		t.Fatalf("expected ErrDuplicateName, got %v", err)
+	}
+	// 其他Heartbeat上可以重名，关闭后名字可再次使用
+	other := newReader()
+	other.heartbeat = common.NewHeartbeat("")
+	if err := other.claimHeartbeatName("billing", "orders"); err != nil {
+		t.Fatal(err)
+	}
+	named.Close()
+	if err := newReader().claimHeartbeatName("billing", "orders"); err != nil {
+		t.Fatalf("name not released on close: %v", err)
+	}
+}
@@ -0,0 +1,80 @@
+package kafkareader
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/cdpzyafk/go-utils/common"
+	"github.com/segmentio/kafka-go"
+	"go.uber.org/zap"
+)
+
+const (
+	RETRYMAXATTEMPTS = 3
+	RETRYBACKOFFMIN  = time.Millisecond * 100
+	RETRYBACKOFFMAX  = time.Second * 5
+)
+
+var (
+	errRetryAborted = errors.New("retry aborted on close")
+)
+
+// RetryPolicy HandlerE失败后在本地重试，用完次数后交给DeadLetter。
+// 重试期间阻塞所在分区(或worker)；Close时停止等待，未处理成功的消息不计入进度，重启后重新处理
+type RetryPolicy struct {
+	MaxAttempts int              // 包括首次调用在内的总次数, default RETRYMAXATTEMPTS
+	BackoffMin  time.Duration    // default RETRYBACKOFFMIN
+	BackoffMax  time.Duration    // default RETRYBACKOFFMAX
+	Retryable   func(error) bool // 返回false的错误不再重试，为空时除ErrDecode外都重试
+}
+
+func (rp *RetryPolicy) withDefaults() *RetryPolicy {
+	policy := *rp
+	if policy.MaxAttempts <= 0 {
+		policy.MaxAttempts = RETRYMAXATTEMPTS
+	}
+	if policy.BackoffMin <= 0 {
+		policy.BackoffMin = RETRYBACKOFFMIN
+	}
+	if policy.BackoffMax <= 0 {
+		policy.BackoffMax = RETRYBACKOFFMAX
+	}
+	return &policy
+}
+
+// retryable 解码失败重试也不会成功，总是不重试
+func (rp *RetryPolicy) retryable(err error) bool {
+	if errors.Is(err, ErrDecode) {
+		return false
+	}
+	return rp.Retryable == nil || rp.Retryable(err)
+}
+
+// call 按策略调用h，返回最后一次的错误；等待重试期间ctx取消时返回包装了errRetryAborted的错误
+func (rp *RetryPolicy) call(ctx context.Context, log *zap.Logger, msg kafka.Message, h func(*zap.Logger, kafka.Message) error) error {
+	var backoff *common.Backoff
+	for attempt := 1; ; attempt++ {
+		err := h(log, msg)
+		if err == nil || attempt >= rp.MaxAttempts || !rp.retryable(err) {
+			return err
+		}
+		if backoff == nil {
+			backoff = common.NewBackoff(rp.BackoffMin, rp.BackoffMax).WithJitter(common.JitterEqual, nil)
+		}
+		wait := backoff.Next()
+		log.Warn("handle message failed, retrying",
+			zap.Int64("offset", msg.Offset),
+			zap.Int("attempt", attempt),
+			zap.Duration("wait", wait),
+			zap.Error(err))
+		timer := time.NewTimer(wait)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return fmt.Errorf("%w: %w", errRetryAborted, err)
+		case <-timer.C:
+		}
+	}
+}
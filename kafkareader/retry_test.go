@@ -0,0 +1,62 @@
+package kafkareader
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/segmentio/kafka-go"
+	"go.uber.org/zap"
+)
+
+func TestRetryPolicyCall(t *testing.T) {
+	policy := (&RetryPolicy{MaxAttempts: 3, BackoffMin: time.Millisecond, BackoffMax: time.Millisecond}).withDefaults()
+	failing := func(failures int, err error) (func(*zap.Logger, kafka.Message) error, *int) {
+		calls := 0
+		return func(*zap.Logger, kafka.Message) error {
+			calls++
+			if calls <= failures {
+				return err
+			}
+			return nil
+		}, &calls
+	}
+
+	h, calls := failing(2, errors.New("flaky"))
+	if err := policy.call(context.Background(), zap.NewNop(), kafka.Message{}, h); err != nil || *calls != 3 {
+		t.Fatalf("err %v after %d calls", err, *calls)
+	}
+	h, calls = failing(5, errors.New("down"))
+	if err := policy.call(context.Background(), zap.NewNop(), kafka.Message{}, h); err == nil || *calls != 3 {
+		t.Fatalf("err %v after %d calls", err, *calls)
+	}
+	h, calls = failing(5, ErrDecode)
+	if err := policy.call(context.Background(), zap.NewNop(), kafka.Message{}, h); !errors.Is(err, ErrDecode) || *calls != 1 {
+		t.Fatalf("decode error retried: err %v after %d calls", err, *calls)
+	}
+}
+
+func TestRetryPolicyAbort(t *testing.T) {
+	r := newTestReader(t, 0)
+	cp := &memCheckpointer{}
+	r.initCheckpoint(cp, time.Minute)
+	dlq := &flakyDeadLetter{}
+	r.dlq = dlq
+	r.retry = (&RetryPolicy{MaxAttempts: 100, BackoffMin: time.Hour, BackoffMax: time.Hour}).withDefaults()
+	r.handleEvent = r.fallible(func(*zap.Logger, kafka.Message) error { return errors.New("down") })
+
+	time.AfterFunc(20*time.Millisecond, r.cancel)
+	start := time.Now()
+	r.handle(zap.NewNop(), kafka.Message{Offset: 3})
+	if d := time.Since(start); d > 5*time.Second {
+		t.Fatalf("retry ignored Close for %v", d)
+	}
+	if len(dlq.sent) != 0 {
+		t.Fatal("abandoned retry was sent to dead letter")
+	}
+	r.Close()
+	if cp.offsets[0] != 3 {
+		t.Fatalf("saved offset %d, want the abandoned message 3", cp.offsets[0])
+	}
+}
@@ -0,0 +1,256 @@
+package kafkareader
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"time"
+
+	"github.com/cdpzyafk/go-utils/common"
+	"github.com/cdpzyafk/go-utils/kafkalib"
+	"github.com/segmentio/kafka-go"
+	"go.uber.org/zap"
+)
+
+const (
+	HeaderRetryAttempt  = "x-retry-attempt"
+	HeaderRetryAt       = "x-retry-at" // unix毫秒，延迟reader在此时间之后才重新处理
+	HeaderOriginalTopic = "x-original-topic"
+	HeaderError         = "x-error"
+
+	FORWARDTIMEOUT = 10 * time.Second
+)
+
+var (
+	ErrNoRetryTiers = errors.New("no retry tiers")
+)
+
+// RetryTier 一层重试topic，如{"orders-retry-5s", 5s}
+type RetryTier struct {
+	Topic string
+	Delay time.Duration
+}
+
+type RetryTopicsConfig struct {
+	Brokers         []string
+	Dialer          *kafka.Dialer // 可选，用于TLS/SASL
+	Tiers           []RetryTier   // 按延迟从短到长排列
+	DeadLetterTopic string        // 所有重试层都失败后写入，为空则丢弃并记录日志
+}
+
+// messageWriter kafka.Writer中用到的方法
+type messageWriter interface {
+	WriteMessages(ctx context.Context, msgs ...kafka.Message) error
+	Close() error
+}
+
+// RetryTopics 分层重试topic：handler失败的消息依次转发到各层重试topic，
+// 由对应的延迟reader在Delay之后重新处理，重试状态保存在消息header中，重启后不丢失
+type RetryTopics struct {
+	writer    messageWriter
+	transport *kafka.Transport // Writer.Close不会释放外部传入的Transport
+	tiers     []RetryTier
+	dlq       string
+	ctx       context.Context // Close时取消，放弃进行中的转发
+	cancel    context.CancelFunc
+}
+
+func NewRetryTopics(cfg *RetryTopicsConfig) (*RetryTopics, error) {
+	if len(cfg.Brokers) == 0 {
+		return nil, ErrNoBrokers
+	}
+	if len(cfg.Tiers) == 0 {
+		return nil, ErrNoRetryTiers
+	}
+	for _, tier := range cfg.Tiers {
+		if tier.Topic == "" {
+			return nil, ErrNoTopic
+		}
+	}
+
+	transport := kafkalib.NewTransport(cfg.Dialer)
+	rt := newRetryTopics(&kafka.Writer{
+		Addr:         kafka.TCP(cfg.Brokers...),
+		Balancer:     &kafka.Hash{},
+		RequiredAcks: kafka.RequireAll,
+		Transport:    transport,
+	}, cfg)
+	rt.transport = transport
+	return rt, nil
+}
+
+func newRetryTopics(writer messageWriter, cfg *RetryTopicsConfig) *RetryTopics {
+	ctx, cancel := context.WithCancel(context.Background())
+	return &RetryTopics{
+		writer: writer,
+		tiers:  cfg.Tiers,
+		dlq:    cfg.DeadLetterTopic,
+		ctx:    ctx,
+		cancel: cancel,
+	}
+}
+
+// Wrap 将返回错误的handler包装为Config.Handler，失败的消息转发到第一层重试topic。
+// 转发失败时退避重试直到成功，期间阻塞所在分区；broker长时间不可用时可调用Close放弃
+func (rt *RetryTopics) Wrap(h func(*zap.Logger, kafka.Message) error) func(*zap.Logger, kafka.Message) {
+	return func(log *zap.Logger, msg kafka.Message) {
+		if err := h(log, msg); err != nil {
+			rt.forward(rt.ctx, log, msg, 0, err)
+		}
+	}
+}
+
+// CreateReaders 为每层重试topic创建延迟重投reader，base中的Topic、Handler会被替换。
+// base未指定StartOffset时，设置了Checkpointer则从保存的位置继续，否则从最早的位置开始，
+// 不会因停机时间较长而跳过未处理的重试；建议设置Checkpointer避免重启后重复处理
+func (rt *RetryTopics) CreateReaders(base *Config, h func(*zap.Logger, kafka.Message) error) ([]*Reader, error) {
+	readers := make([]*Reader, 0, len(rt.tiers))
+	for i := range rt.tiers {
+		cfg := rt.readerConfig(base, i)
+		r, err := CreateReader(&cfg)
+		if err != nil {
+			for _, r := range readers {
+				r.Close()
+			}
+			return nil, err
+		}
+		// handler需要等待reader的ctx，创建后再替换
+		r.handleEvent = r.timed(rt.delayedHandler(r, i, h))
+		readers = append(readers, r)
+	}
+	return readers, nil
+}
+
+func (rt *RetryTopics) readerConfig(base *Config, tier int) Config {
+	cfg := *base
+	cfg.Topic = rt.tiers[tier].Topic
+	cfg.Handler = func(*zap.Logger, kafka.Message) {}
+	if cfg.Name != "" {
+		cfg.Name += "-" + cfg.Topic
+	}
+	if cfg.StartOffset == nil {
+		if cfg.Checkpointer != nil {
+			cfg.StartOffset = ResumeFrom(cfg.Checkpointer.Load, StartAtFirst())
+		} else {
+			cfg.StartOffset = StartAtFirst()
+		}
+	}
+	return cfg
+}
+
+// Close 放弃进行中的转发，应在使用它的Reader关闭之后调用
+func (rt *RetryTopics) Close() error {
+	rt.cancel()
+	err := rt.writer.Close()
+	if rt.transport != nil {
+		rt.transport.CloseIdleConnections()
+	}
+	return err
+}
+
+// delayedHandler 等到消息的retry-at时间后再调用h，同一层的retry-at单调递增，阻塞等待即可。
+// 等待或转发期间Reader关闭时，消息不计入处理进度，重启后重新处理
+func (rt *RetryTopics) delayedHandler(r *Reader, tier int, h func(*zap.Logger, kafka.Message) error) func(*zap.Logger, kafka.Message) {
+	return func(log *zap.Logger, msg kafka.Message) {
+		if at, ok := headerInt(msg, HeaderRetryAt); ok && !r.delay(msg.Partition, time.Until(time.UnixMilli(at))) {
+			r.holdOffset(msg)
+			return
+		}
+		if err := h(log, msg); err != nil && rt.forward(r.ctx, log, msg, tier+1, err) != nil {
+			r.holdOffset(msg)
+		}
+	}
+}
+
+// forward 将消息写入第tier层重试topic，超过最后一层时写入死信topic；
+// 写入失败时退避重试，直到成功或ctx取消、RetryTopics关闭
+func (rt *RetryTopics) forward(ctx context.Context, log *zap.Logger, msg kafka.Message, tier int, cause error) error {
+	topic := rt.dlq
+	headers := setHeader(msg.Headers, HeaderError, cause.Error())
+	headers = setHeader(headers, HeaderRetryAttempt, strconv.Itoa(tier))
+	if _, ok := header(msg, HeaderOriginalTopic); !ok {
+		headers = setHeader(headers, HeaderOriginalTopic, msg.Topic)
+	}
+	if tier < len(rt.tiers) {
+		topic = rt.tiers[tier].Topic
+		at := time.Now().Add(rt.tiers[tier].Delay).UnixMilli()
+		headers = setHeader(headers, HeaderRetryAt, strconv.FormatInt(at, 10))
+	}
+
+	log = log.With(zap.Int64("offset", msg.Offset), zap.Int("tier", tier), zap.Error(cause))
+	if topic == "" {
+		log.Error("retries exhausted, message dropped")
+		return nil
+	}
+
+	forwarded := kafka.Message{
+		Topic:   topic,
+		Key:     msg.Key,
+		Value:   msg.Value,
+		Headers: headers,
+	}
+	var backoff *common.Backoff
+	for {
+		writeCtx, cancel := context.WithTimeout(ctx, FORWARDTIMEOUT)
+		err := rt.writer.WriteMessages(writeCtx, forwarded)
+		cancel()
+		if err == nil {
+			return nil
+		}
+		if backoff == nil {
+			backoff = common.NewBackoff(RETRYBACKOFFMIN, RETRYBACKOFFMAX).WithJitter(common.JitterEqual, nil)
+		}
+		wait := backoff.Next()
+		log.Error("forward to retry topic failed, retrying",
+			zap.String("topic", topic),
+			zap.Duration("wait", wait),
+			zap.NamedError("writeErr", err))
+		if err = sleepCtx(wait, ctx, rt.ctx); err != nil {
+			log.Error("forward to retry topic abandoned", zap.String("topic", topic))
+			return err
+		}
+	}
+}
+
+// sleepCtx 等待d，任一ctx取消时返回其错误
+func sleepCtx(d time.Duration, ctx, other context.Context) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-other.Done():
+		return other.Err()
+	case <-timer.C:
+		return nil
+	}
+}
+
+func header(msg kafka.Message, key string) (string, bool) {
+	for _, h := range msg.Headers {
+		if h.Key == key {
+			return string(h.Value), true
+		}
+	}
+	return "", false
+}
+
+func headerInt(msg kafka.Message, key string) (int64, bool) {
+	v, ok := header(msg, key)
+	if !ok {
+		return 0, false
+	}
+	n, err := strconv.ParseInt(v, 10, 64)
+	return n, err == nil
+}
+
+// setHeader 返回新的header切片，不修改原消息
+func setHeader(headers []kafka.Header, key, value string) []kafka.Header {
+	r := make([]kafka.Header, 0, len(headers)+1)
+	for _, h := range headers {
+		if h.Key != key {
+			r = append(r, h)
+		}
+	}
+	return append(r, kafka.Header{Key: key, Value: []byte(value)})
+}
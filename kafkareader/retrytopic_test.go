@@ -0,0 +1,165 @@
+package kafkareader
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/segmentio/kafka-go"
+	"go.uber.org/zap"
+)
+
+// fakeWriter 前failures次写入失败
+type fakeWriter struct {
+	mu       sync.Mutex
+	failures int
+	attempts int
+	written  []kafka.Message
+}
+
+func (fw *fakeWriter) WriteMessages(_ context.Context, msgs ...kafka.Message) error {
+	fw.mu.Lock()
+	defer fw.mu.Unlock()
+	fw.attempts++
+	if fw.failures < 0 || fw.attempts <= fw.failures {
+		return errors.New("broker unavailable")
+	}
+	fw.written = append(fw.written, msgs...)
+	return nil
+}
+
+func (fw *fakeWriter) Close() error { return nil }
+
+func newTestRetryTopics(writer messageWriter, dlq string) *RetryTopics {
+	return newRetryTopics(writer, &RetryTopicsConfig{
+		Tiers:           []RetryTier{{Topic: "retry-1s", Delay: time.Second}, {Topic: "retry-1m", Delay: time.Minute}},
+		DeadLetterTopic: dlq,
+	})
+}
+
+func TestRetryTopicsForward(t *testing.T) {
+	writer := &fakeWriter{failures: 2}
+	rt := newTestRetryTopics(writer, "dlq")
+	defer rt.Close()
+	msg := kafka.Message{Topic: "orders", Offset: 7, Key: []byte("k"), Value: []byte("v")}
+
+	before := time.Now()
+	if err := rt.forward(context.Background(), zap.NewNop(), msg, 1, errors.New("boom")); err != nil {
+		t.Fatal(err)
+	}
+	if writer.attempts != 3 || len(writer.written) != 1 {
+		t.Fatalf("attempts %d written %d", writer.attempts, len(writer.written))
+	}
+	forwarded := writer.written[0]
+	if forwarded.Topic != "retry-1m" {
+		t.Fatalf("forwarded to %s", forwarded.Topic)
+	}
+	for key, want := range map[string]string{HeaderRetryAttempt: "1", HeaderOriginalTopic: "orders", HeaderError: "boom"} {
+		if v, _ := header(forwarded, key); v != want {
+			t.Fatalf("header %s = %q, want %q", key, v, want)
+		}
+	}
+	at, _ := headerInt(forwarded, HeaderRetryAt)
+	if retryAt := time.UnixMilli(at); retryAt.Before(before.Add(time.Minute - time.Second)) {
+		t.Fatalf("retry at %v", retryAt)
+	}
+
+	// 超过最后一层写入死信topic，不再带retry-at
+	if err := rt.forward(context.Background(), zap.NewNop(), forwarded, 2, errors.New("boom")); err != nil {
+		t.Fatal(err)
+	}
+	last := writer.written[1]
+	if v, _ := header(last, HeaderOriginalTopic); last.Topic != "dlq" || v != "orders" {
+		t.Fatalf("dead letter %s original topic %s", last.Topic, v)
+	}
+
+	// 没有死信topic时丢弃
+	rt = newTestRetryTopics(writer, "")
+	if err := rt.forward(context.Background(), zap.NewNop(), msg, 2, errors.New("boom")); err != nil || len(writer.written) != 2 {
+		t.Fatalf("dropped message was written, err %v", err)
+	}
+}
+
+func TestRetryTopicsForwardAbandon(t *testing.T) {
+	rt := newTestRetryTopics(&fakeWriter{failures: -1}, "dlq")
+	time.AfterFunc(50*time.Millisecond, func() { rt.Close() })
+
+	done := make(chan error)
+	go func() { done <- rt.forward(rt.ctx, zap.NewNop(), kafka.Message{}, 0, errors.New("boom")) }()
+	select {
+	case err := <-done:
+		if !errors.Is(err, context.Canceled) {
+			t.Fatalf("expected canceled, got %v", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("forward kept retrying after Close")
+	}
+}
+
+func TestRetryTopicsDelayedHandler(t *testing.T) {
+	writer := &fakeWriter{}
+	rt := newTestRetryTopics(writer, "dlq")
+	defer rt.Close()
+	r := newTestReader(t, 0)
+	cp := &memCheckpointer{}
+	r.initCheckpoint(cp, time.Minute)
+
+	var calls int
+	handler := rt.delayedHandler(r, 0, func(*zap.Logger, kafka.Message) error {
+		calls++
+		return errors.New("still failing")
+	})
+	r.handleEvent = handler
+	retryAt := func(d time.Duration) []kafka.Header {
+		return []kafka.Header{{Key: HeaderRetryAt, Value: []byte(strconv.FormatInt(time.Now().Add(d).UnixMilli(), 10))}}
+	}
+
+	// 已到期的消息立即处理，失败后转发到下一层
+	due := kafka.Message{Topic: "retry-1s", Offset: 4, Headers: retryAt(-time.Second)}
+	r.handle(zap.NewNop(), due)
+	if calls != 1 || len(writer.written) != 1 || writer.written[0].Topic != "retry-1m" {
+		t.Fatalf("calls %d written %+v", calls, writer.written)
+	}
+
+	// 未到期时Close，等待立即结束，消息不计入进度
+	pending := kafka.Message{Topic: "retry-1s", Offset: 5, Headers: retryAt(time.Hour)}
+	time.AfterFunc(20*time.Millisecond, r.cancel)
+	start := time.Now()
+	handler(zap.NewNop(), pending)
+	if d := time.Since(start); d > time.Second {
+		t.Fatalf("delayed handler ignored Close for %v", d)
+	}
+	if calls != 1 {
+		t.Fatal("handler called before retry-at")
+	}
+	r.markHandled(kafka.Message{Offset: 6})
+	r.Close()
+	if cp.offsets[0] != 5 {
+		t.Fatalf("saved offset %d, want 5", cp.offsets[0])
+	}
+}
+
+func TestRetryTopicsReaderConfig(t *testing.T) {
+	rt := newTestRetryTopics(&fakeWriter{}, "")
+	defer rt.Close()
+
+	cfg := rt.readerConfig(&Config{Name: "orders"}, 1)
+	if cfg.Topic != "retry-1m" || cfg.Name != "orders-retry-1m" {
+		t.Fatalf("topic %s name %s", cfg.Topic, cfg.Name)
+	}
+	if _, ok := cfg.StartOffset.(firstOffset); !ok {
+		t.Fatalf("default start offset %T", cfg.StartOffset)
+	}
+
+	cfg = rt.readerConfig(&Config{Checkpointer: &memCheckpointer{}}, 0)
+	resume, ok := cfg.StartOffset.(resumeOffset)
+	if !ok {
+		t.Fatalf("start offset with checkpointer %T", cfg.StartOffset)
+	}
+	if _, ok := resume.fallback.(firstOffset); !ok {
+		t.Fatalf("resume fallback %T", resume.fallback)
+	}
+}
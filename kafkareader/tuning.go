@@ -0,0 +1,120 @@
+package kafkareader
+
+import (
+	"fmt"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// TuningPatch 运行时调整的参数，nil字段保持不变
+type TuningPatch struct {
+	MinBytes       *int
+	MaxBytes       *int
+	ReadBackoffMin *time.Duration
+
+	Concurrency          *int     // 各分区在下一轮拉取前等已排队的消息处理完，再以新的worker数重建
+	MaxMessagesPerSecond *float64 // 0表示不限速
+	RateBurst            *int     // 0表示向上取整的MaxMessagesPerSecond
+}
+
+// ApplyTuning 校验并应用参数。拉取参数变更时各分区在下一轮拉取时以新参数重建kafka reader，
+// 从已拉取到的位置继续消费，不丢失内存中的状态；限速变更立即生效
+func (p *Reader) ApplyTuning(patch TuningPatch) error {
+	p.tuneMu.Lock()
+	minBytes, maxBytes, backoff := p.minBytes, p.maxBytes, p.readBackoffMin
+	concurrency, perSecond, burst := max(p.concurrency, 1), p.maxPerSecond, p.rateBurst
+	if patch.MinBytes != nil {
+		minBytes = *patch.MinBytes
+	}
+	if patch.MaxBytes != nil {
+		maxBytes = *patch.MaxBytes
+	}
+	if patch.ReadBackoffMin != nil {
+		backoff = *patch.ReadBackoffMin
+	}
+	if patch.Concurrency != nil {
+		concurrency = *patch.Concurrency
+	}
+	if patch.MaxMessagesPerSecond != nil {
+		perSecond = *patch.MaxMessagesPerSecond
+	}
+	if patch.RateBurst != nil {
+		burst = *patch.RateBurst
+	}
+	if err := p.validateTuning(minBytes, maxBytes, backoff, concurrency, perSecond, burst); err != nil {
+		p.tuneMu.Unlock()
+		return err
+	}
+
+	p.log.Info("apply tuning",
+		zap.Int("minBytes", minBytes), zap.Int("oldMinBytes", p.minBytes),
+		zap.Int("maxBytes", maxBytes), zap.Int("oldMaxBytes", p.maxBytes),
+		zap.Duration("readBackoffMin", backoff), zap.Duration("oldReadBackoffMin", p.readBackoffMin),
+		zap.Int("concurrency", concurrency), zap.Int("oldConcurrency", p.concurrency),
+		zap.Float64("maxMessagesPerSecond", perSecond), zap.Float64("oldMaxMessagesPerSecond", p.maxPerSecond),
+		zap.Int("rateBurst", burst), zap.Int("oldRateBurst", p.rateBurst))
+	fetchChanged := minBytes != p.minBytes || maxBytes != p.maxBytes || backoff != p.readBackoffMin
+	workersChanged := concurrency != max(p.concurrency, 1)
+	rateChanged := perSecond != p.maxPerSecond || burst != p.rateBurst
+	p.minBytes, p.maxBytes, p.readBackoffMin = minBytes, maxBytes, backoff
+	p.concurrency, p.maxPerSecond, p.rateBurst = concurrency, perSecond, burst
+	if rateChanged {
+		if perSecond > 0 {
+			p.rateLimiter.Store(newRateLimiter(perSecond, burst, p.ratePerReader))
+		} else {
+			p.rateLimiter.Store(nil)
+		}
+	}
+	p.tuneMu.Unlock()
+
+	if fetchChanged || workersChanged || rateChanged {
+		p.tuningChanges.Add(1)
+	}
+	for _, reader := range p.readers {
+		if fetchChanged {
+			reader.retune.Store(true)
+		}
+		if workersChanged {
+			reader.rework.Store(true)
+		}
+	}
+	return nil
+}
+
+// validateTuning 需持有tuneMu
+func (p *Reader) validateTuning(minBytes, maxBytes int, backoff time.Duration, concurrency int, perSecond float64, burst int) error {
+	if minBytes <= 0 || maxBytes < minBytes {
+		return fmt.Errorf("%w: min bytes %d, max bytes %d", ErrInvalidTuning, minBytes, maxBytes)
+	}
+	if backoff < READBACKOFFMIN {
+		return fmt.Errorf("%w: read backoff %v below %v", ErrInvalidTuning, backoff, READBACKOFFMIN)
+	}
+	if concurrency < 1 {
+		return fmt.Errorf("%w: concurrency %d", ErrInvalidTuning, concurrency)
+	}
+	if concurrency != max(p.concurrency, 1) && (p.batchHandler != nil || p.merger != nil) {
+		return fmt.Errorf("%w: concurrency cannot be used with BatchHandler or Ordered", ErrInvalidTuning)
+	}
+	if perSecond < 0 || burst < 0 {
+		return fmt.Errorf("%w: max messages per second %v, burst %d", ErrInvalidTuning, perSecond, burst)
+	}
+	return nil
+}
+
+// TuningChanges 返回ApplyTuning实际改变参数的次数
+func (p *Reader) TuningChanges() int64 {
+	return p.tuningChanges.Load()
+}
+
+func (p *Reader) fetchTuning() (minBytes, maxBytes int, readBackoffMin time.Duration) {
+	p.tuneMu.RLock()
+	defer p.tuneMu.RUnlock()
+	return p.minBytes, p.maxBytes, p.readBackoffMin
+}
+
+func (p *Reader) workerConcurrency() int {
+	p.tuneMu.RLock()
+	defer p.tuneMu.RUnlock()
+	return max(p.concurrency, 1)
+}
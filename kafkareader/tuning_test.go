@@ -0,0 +1,102 @@
+package kafkareader
+
+import (
+	"errors"
+	"sync/atomic"
+	"testing"
+
+	"github.com/segmentio/kafka-go"
+	"go.uber.org/zap"
+)
+
+func TestApplyTuning(t *testing.T) {
+	r := newTestReader(t, 0)
+	defer r.Close()
+	pr := r.readers[0]
+	ptr := func(n int) *int { return &n }
+	rate := func(v float64) *float64 { return &v }
+
+	if err := r.ApplyTuning(TuningPatch{MaxBytes: ptr(MINBYTES - 1)}); !errors.Is(err, ErrInvalidTuning) {
+		t.Fatalf("expected ErrInvalidTuning, got %v", err)
+	}
+	if err := r.ApplyTuning(TuningPatch{Concurrency: ptr(0)}); !errors.Is(err, ErrInvalidTuning) {
+		t.Fatalf("expected ErrInvalidTuning, got %v", err)
+	}
+	if err := r.ApplyTuning(TuningPatch{MaxMessagesPerSecond: rate(-1)}); !errors.Is(err, ErrInvalidTuning) {
+		t.Fatalf("expected ErrInvalidTuning, got %v", err)
+	}
+
+	// 未变化的参数不触发重建
+	if err := r.ApplyTuning(TuningPatch{MinBytes: ptr(MINBYTES), Concurrency: ptr(1)}); err != nil {
+		t.Fatal(err)
+	}
+	if r.TuningChanges() != 0 || pr.retune.Load() || pr.rework.Load() {
+		t.Fatal("unchanged patch triggered a rebuild")
+	}
+
+	if err := r.ApplyTuning(TuningPatch{MinBytes: ptr(MINBYTES * 2)}); err != nil {
+		t.Fatal(err)
+	}
+	if !pr.retune.Load() || pr.rework.Load() {
+		t.Fatal("fetch change should only retune the reader")
+	}
+
+	// 限速立即生效，0表示不限速
+	if err := r.ApplyTuning(TuningPatch{MaxMessagesPerSecond: rate(10), RateBurst: ptr(5)}); err != nil {
+		t.Fatal(err)
+	}
+	if rl := r.rateLimiter.Load(); rl == nil {
+		t.Fatal("rate limiter not installed")
+	}
+	if err := r.ApplyTuning(TuningPatch{MaxMessagesPerSecond: rate(0)}); err != nil {
+		t.Fatal(err)
+	}
+	if r.rateLimiter.Load() != nil {
+		t.Fatal("rate limiter not removed")
+	}
+	if r.TuningChanges() != 3 {
+		t.Fatalf("tuning changes %d", r.TuningChanges())
+	}
+}
+
+func TestApplyTuningConcurrency(t *testing.T) {
+	r := newTestReader(t, 0)
+	var handled atomic.Int64
+	r.handleEvent = func(*zap.Logger, kafka.Message) { handled.Add(1) }
+	pr := r.readers[0]
+	ptr := func(n int) *int { return &n }
+	pr.restartWorkers()
+	if pr.workers != nil {
+		t.Fatal("workers started without BufferSize or Concurrency")
+	}
+
+	if err := r.ApplyTuning(TuningPatch{Concurrency: ptr(4)}); err != nil {
+		t.Fatal(err)
+	}
+	if !pr.rework.Load() {
+		t.Fatal("concurrency change did not mark workers for rebuild")
+	}
+	pr.restartWorkers()
+	if pr.workers == nil {
+		t.Fatal("workers not started after raising concurrency")
+	}
+	for offset := int64(0); offset < 100; offset++ {
+		pr.submit(kafka.Message{Offset: offset})
+	}
+	// 重建时等待已排队的消息处理完
+	pr.restartWorkers()
+	if handled.Load() != 100 {
+		t.Fatalf("handled %d before rebuild", handled.Load())
+	}
+	pr.stopWorkers()
+	if pr.workers != nil {
+		t.Fatal("workers not cleared after stop")
+	}
+
+	r.batchHandler = func(*zap.Logger, []kafka.Message) {}
+	if err := r.ApplyTuning(TuningPatch{Concurrency: ptr(2)}); !errors.Is(err, ErrInvalidTuning) {
+		t.Fatalf("expected ErrInvalidTuning with BatchHandler, got %v", err)
+	}
+	r.batchHandler = nil
+	r.Close()
+}
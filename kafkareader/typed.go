@@ -0,0 +1,77 @@
+package kafkareader
+
+import (
+	"fmt"
+
+	"github.com/bytedance/sonic"
+	"github.com/segmentio/kafka-go"
+	"go.uber.org/zap"
+)
+
+// Decoder 将kafka.Message.Value解码为T，protobuf、Avro等格式自行实现
+type Decoder[T any] interface {
+	Decode(data []byte) (T, error)
+}
+
+// DecoderFunc 将函数适配为Decoder
+type DecoderFunc[T any] func(data []byte) (T, error)
+
+func (f DecoderFunc[T]) Decode(data []byte) (T, error) {
+	return f(data)
+}
+
+// JSONDecoder 默认的json解码
+func JSONDecoder[T any]() Decoder[T] {
+	return DecoderFunc[T](func(data []byte) (T, error) {
+		var v T
+		err := sonic.Unmarshal(data, &v)
+		return v, err
+	})
+}
+
+// TypedConfig TypedReader的解码和处理设置，连接、offset等设置沿用Config
+type TypedConfig[T any] struct {
+	Decoder  Decoder[T]    // default JSONDecoder
+	Validate func(T) error // 解码后校验，失败与解码失败同样处理
+	Handler  func(*zap.Logger, T, kafka.Message)
+	// OnDecodeError 解码或校验失败时回调；为空时错误交给Config.DeadLetter，不会按RetryPolicy重试
+	OnDecodeError func(*zap.Logger, kafka.Message, error)
+}
+
+// TypedReader 在调用handler前将消息解码为T
+type TypedReader[T any] struct {
+	*Reader
+}
+
+// CreateTypedReader cfg中不能再设置Handler、HandlerE或BatchHandler
+func CreateTypedReader[T any](cfg *Config, typed TypedConfig[T]) (*TypedReader[T], error) {
+	if typed.Handler == nil {
+		return nil, ErrNoHandler
+	}
+	decoder := typed.Decoder
+	if decoder == nil {
+		decoder = JSONDecoder[T]()
+	}
+
+	cfg.HandlerE = func(log *zap.Logger, msg kafka.Message) error {
+		v, err := decoder.Decode(msg.Value)
+		if err == nil && typed.Validate != nil {
+			err = typed.Validate(v)
+		}
+		if err != nil {
+			err = fmt.Errorf("%w: %v", ErrDecode, err)
+			if typed.OnDecodeError != nil {
+				typed.OnDecodeError(log, msg, err)
+				return nil
+			}
+			return err
+		}
+		typed.Handler(log, v, msg)
+		return nil
+	}
+	r, err := CreateReader(cfg)
+	if err != nil {
+		return nil, err
+	}
+	return &TypedReader[T]{Reader: r}, nil
+}
@@ -0,0 +1,132 @@
+package kafkareader
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/segmentio/kafka-go"
+)
+
+const (
+	WATERMARKTIMEOUT = time.Second * 5
+	WATERMARKIDLE    = time.Second * 2
+)
+
+// WatermarkConfig 按事件时间对齐投递：消息仅在所有分区都已拉取到不早于其时间戳(减去MaxSkew)的消息后才交给handler
+type WatermarkConfig struct {
+	MaxSkew   time.Duration // 允许各分区间的事件时间偏差
+	Timeout   time.Duration // 等待落后分区的最长时间，超时后直接投递, default WATERMARKTIMEOUT
+	IdleAfter time.Duration // 分区超过这么久没有推进视为空闲，不再阻塞其他分区, default WATERMARKIDLE
+}
+
+// partitionMark 分区的水位，moved为水位最近一次推进的时间
+type partitionMark struct {
+	t      time.Time
+	moved  time.Time
+	paused bool
+}
+
+type watermark struct {
+	mu        sync.Mutex
+	marks     map[int]*partitionMark
+	changed   chan struct{}
+	maxSkew   time.Duration
+	timeout   time.Duration
+	idleAfter time.Duration
+}
+
+func newWatermark(cfg *WatermarkConfig, partitions []kafka.Partition) *watermark {
+	w := &watermark{
+		marks:     make(map[int]*partitionMark, len(partitions)),
+		changed:   make(chan struct{}),
+		maxSkew:   cfg.MaxSkew,
+		timeout:   cfg.Timeout,
+		idleAfter: cfg.IdleAfter,
+	}
+	if w.timeout <= 0 {
+		w.timeout = WATERMARKTIMEOUT
+	}
+	if w.idleAfter <= 0 {
+		w.idleAfter = WATERMARKIDLE
+	}
+	now := time.Now()
+	for _, p := range partitions {
+		w.marks[p.ID] = &partitionMark{moved: now}
+	}
+	return w
+}
+
+func (w *watermark) advance(partition int, t time.Time) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if m := w.marks[partition]; m != nil && t.After(m.t) {
+		m.t, m.moved = t, time.Now()
+		w.notify()
+	}
+}
+
+// setPaused 暂停的分区不参与对齐，恢复后重新计算空闲时间
+func (w *watermark) setPaused(partition int, paused bool) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if m := w.marks[partition]; m != nil && m.paused != paused {
+		m.paused, m.moved = paused, time.Now()
+		w.notify()
+	}
+}
+
+// notify 唤醒等待中的分区，需持有锁
+func (w *watermark) notify() {
+	close(w.changed)
+	w.changed = make(chan struct{})
+}
+
+// blocking 返回阻塞until的活跃分区中最早变为空闲的时间，没有阻塞的分区时ok为false；需持有锁
+func (w *watermark) blocking(until, now time.Time) (idleAt time.Time, ok bool) {
+	for _, m := range w.marks {
+		if m.paused || !m.t.Before(until) {
+			continue
+		}
+		at := m.moved.Add(w.idleAfter)
+		if !at.After(now) {
+			continue // 空闲
+		}
+		if !ok || at.Before(idleAt) {
+			idleAt, ok = at, true
+		}
+	}
+	return
+}
+
+// wait 推进本分区水位后等待其他活跃分区追上t，返回是否因超时放行；ctx取消时立即返回
+func (w *watermark) wait(ctx context.Context, partition int, t time.Time) (timeout bool) {
+	w.advance(partition, t)
+
+	until := t.Add(-w.maxSkew)
+	deadline := time.Now().Add(w.timeout)
+	timer := time.NewTimer(w.timeout)
+	defer timer.Stop()
+	for {
+		now := time.Now()
+		w.mu.Lock()
+		idleAt, blocked := w.blocking(until, now)
+		changed := w.changed
+		w.mu.Unlock()
+		if !blocked {
+			return false
+		}
+		if !now.Before(deadline) {
+			return true
+		}
+
+		// 在落后分区变为空闲或超时时重新检查
+		timer.Reset(min(idleAt.Sub(now), deadline.Sub(now)))
+		select {
+		case <-ctx.Done():
+			return false
+		case <-changed:
+		case <-timer.C:
+		}
+	}
+}
@@ -0,0 +1,104 @@
+package kafkareader
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/segmentio/kafka-go"
+)
+
+func newTestWatermark(cfg WatermarkConfig, n int) *watermark {
+	partitions := make([]kafka.Partition, n)
+	for i := range partitions {
+		partitions[i].ID = i
+	}
+	return newWatermark(&cfg, partitions)
+}
+
+func TestWatermarkIdle(t *testing.T) {
+	w := newTestWatermark(WatermarkConfig{Timeout: time.Minute, IdleAfter: 50 * time.Millisecond}, 2)
+	now := time.Now()
+
+	// 分区1从未推进，空闲后分区0不再等待它
+	start := time.Now()
+	if w.wait(context.Background(), 0, now) {
+		t.Fatal("expected release by idle, not timeout")
+	}
+	if d := time.Since(start); d > time.Second {
+		t.Fatalf("waited %v for an idle partition", d)
+	}
+	// 已空闲的分区不再阻塞后续消息
+	start = time.Now()
+	w.wait(context.Background(), 0, now.Add(time.Second))
+	if d := time.Since(start); d > 20*time.Millisecond {
+		t.Fatalf("waited %v again for an idle partition", d)
+	}
+}
+
+func TestWatermarkPaused(t *testing.T) {
+	w := newTestWatermark(WatermarkConfig{Timeout: time.Minute, IdleAfter: time.Minute}, 2)
+	w.setPaused(1, true)
+
+	start := time.Now()
+	w.wait(context.Background(), 0, time.Now())
+	if d := time.Since(start); d > 20*time.Millisecond {
+		t.Fatalf("waited %v for a paused partition", d)
+	}
+
+	// 恢复后重新参与对齐
+	w.setPaused(1, false)
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	w.wait(ctx, 0, time.Now())
+	if ctx.Err() == nil {
+		t.Fatal("resumed partition should block until it advances")
+	}
+}
+
+func TestWatermarkSkew(t *testing.T) {
+	w := newTestWatermark(WatermarkConfig{MaxSkew: time.Second, Timeout: time.Minute, IdleAfter: time.Minute}, 2)
+	base := time.Now()
+	w.advance(1, base)
+
+	// 偏差在MaxSkew内直接放行
+	start := time.Now()
+	w.wait(context.Background(), 0, base.Add(time.Second))
+	if d := time.Since(start); d > 20*time.Millisecond {
+		t.Fatalf("waited %v within max skew", d)
+	}
+
+	// 超出MaxSkew时等到落后分区追上
+	done := make(chan bool)
+	go func() { done <- w.wait(context.Background(), 0, base.Add(3*time.Second)) }()
+	select {
+	case <-done:
+		t.Fatal("released before the lagging partition caught up")
+	case <-time.After(30 * time.Millisecond):
+	}
+	w.advance(1, base.Add(2*time.Second))
+	select {
+	case timeout := <-done:
+		if timeout {
+			t.Fatal("expected release by catch up, not timeout")
+		}
+	case <-time.After(time.Second):
+		t.Fatal("not released after the lagging partition caught up")
+	}
+}
+
+func TestWatermarkTimeoutAndCancel(t *testing.T) {
+	w := newTestWatermark(WatermarkConfig{Timeout: 30 * time.Millisecond, IdleAfter: time.Minute}, 2)
+	if !w.wait(context.Background(), 0, time.Now()) {
+		t.Fatal("expected timeout")
+	}
+
+	w = newTestWatermark(WatermarkConfig{Timeout: time.Minute, IdleAfter: time.Minute}, 2)
+	ctx, cancel := context.WithCancel(context.Background())
+	time.AfterFunc(20*time.Millisecond, cancel)
+	start := time.Now()
+	w.wait(ctx, 0, time.Now())
+	if d := time.Since(start); d > time.Second {
+		t.Fatalf("wait ignored ctx for %v", d)
+	}
+}
@@ -0,0 +1,120 @@
+package kafkareader
+
+import (
+	"strconv"
+	"sync"
+
+	"github.com/cdpzyafk/go-utils/common"
+	"github.com/cdpzyafk/go-utils/logutil"
+	"github.com/segmentio/kafka-go"
+	"go.uber.org/zap"
+)
+
+const (
+	WORKERQUEUE = 64 // Concurrency>1且未设置BufferSize时每个队列的消息数上限
+)
+
+// partitionWorkers 单个分区的handler worker，队列即拉取与处理之间的缓冲，满时阻塞拉取形成背压
+type partitionWorkers struct {
+	queues  []chan kafka.Message // 按key保序时每个worker一个队列，否则共用一个
+	wg      sync.WaitGroup
+	tracker offsetTracker
+}
+
+// restartWorkers 等待已排队的消息处理完后按当前Concurrency重建worker，
+// 未设置BufferSize且Concurrency为1时在拉取goroutine中同步处理
+func (pr *PartitionReader) restartWorkers() {
+	pr.stopWorkers()
+	p := pr.parent
+	n := p.workerConcurrency()
+	queue := p.bufferSize
+	if queue <= 0 && n > 1 {
+		queue = WORKERQUEUE
+	}
+	if queue > 0 {
+		pr.startWorkers(n, queue, p.keyOrdered)
+	}
+}
+
+func (pr *PartitionReader) startWorkers(n, queue int, byKey bool) {
+	w := &partitionWorkers{}
+	queues := 1
+	if byKey {
+		queues = n
+	}
+	for i := 0; i < queues; i++ {
+		w.queues = append(w.queues, make(chan kafka.Message, queue))
+	}
+	w.wg.Add(n)
+	for i := 0; i < n; i++ {
+		lg := pr.log
+		if byKey {
+			// 按key保序时每个worker对应固定的一组key，日志带上lane便于追踪同一key的处理
+			lg = logutil.WithLane(logutil.ContextWithLane(pr.ctx, strconv.Itoa(i)), lg)
+		}
+		go pr.work(w, w.queues[i%queues], lg)
+	}
+	pr.workers = w
+}
+
+func (pr *PartitionReader) work(w *partitionWorkers, queue <-chan kafka.Message, lg *zap.Logger) {
+	defer w.wg.Done()
+	for msg := range queue {
+		pr.parent.handleEvent(lg, msg)
+		if next, ok := w.tracker.done(msg.Offset); ok {
+			pr.parent.markOffset(pr.partition.ID, next)
+		}
+	}
+}
+
+// submit 交给worker处理，同一key的消息总是由同一worker按顺序处理
+func (pr *PartitionReader) submit(msg kafka.Message) {
+	w := pr.workers
+	w.tracker.add(msg.Offset)
+	queue := w.queues[0]
+	if len(w.queues) > 1 {
+		queue = w.queues[common.HashKey(string(msg.Key))%uint64(len(w.queues))]
+	}
+	queue <- msg
+}
+
+// stopWorkers 等待已排队的消息处理完
+func (pr *PartitionReader) stopWorkers() {
+	if w := pr.workers; w != nil {
+		for _, queue := range w.queues {
+			close(queue)
+		}
+		w.wg.Wait()
+		pr.workers = nil
+	}
+}
+
+// offsetTracker 消息并发处理、乱序完成时，计算可以安全保存的进度：
+// 所有更早的消息都已处理完的位置
+type offsetTracker struct {
+	mu       sync.Mutex
+	pending  []int64 // 按offset递增排列的未完成消息
+	finished map[int64]bool
+}
+
+func (t *offsetTracker) add(offset int64) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.finished == nil {
+		t.finished = make(map[int64]bool)
+	}
+	t.pending = append(t.pending, offset)
+}
+
+// done 标记offset处理完，最早的未完成消息前移时返回新的进度
+func (t *offsetTracker) done(offset int64) (next int64, advanced bool) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.finished[offset] = true
+	for len(t.pending) > 0 && t.finished[t.pending[0]] {
+		delete(t.finished, t.pending[0])
+		next, advanced = t.pending[0]+1, true
+		t.pending = t.pending[1:]
+	}
+	return
+}
@@ -0,0 +1,50 @@
+package kafkareader
+
+import (
+	"testing"
+)
+
+func TestOffsetTracker(t *testing.T) {
+	type step struct {
+		done     int64
+		next     int64
+		advanced bool
+	}
+	for _, tc := range []struct {
+		name    string
+		pending []int64
+		steps   []step
+	}{
+		{
+			name:    "in order",
+			pending: []int64{1, 2, 3},
+			steps:   []step{{1, 2, true}, {2, 3, true}, {3, 4, true}},
+		},
+		{
+			name:    "out of order",
+			pending: []int64{1, 2, 3, 4},
+			steps:   []step{{3, 0, false}, {2, 0, false}, {1, 4, true}, {4, 5, true}},
+		},
+		{
+			name:    "gaps in offsets",
+			pending: []int64{10, 15, 20},
+			steps:   []step{{15, 0, false}, {10, 16, true}, {20, 21, true}},
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			var tracker offsetTracker
+			for _, offset := range tc.pending {
+				tracker.add(offset)
+			}
+			for _, s := range tc.steps {
+				next, advanced := tracker.done(s.done)
+				if advanced != s.advanced || (advanced && next != s.next) {
+					t.Fatalf("done(%d) = %d, %v; want %d, %v", s.done, next, advanced, s.next, s.advanced)
+				}
+			}
+			if len(tracker.pending) != 0 || len(tracker.finished) != 0 {
+				t.Fatalf("leftover pending %v finished %v", tracker.pending, tracker.finished)
+			}
+		})
+	}
+}
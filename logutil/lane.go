@@ -0,0 +1,75 @@
+package logutil
+
+import (
+	"context"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+// LaneKey is the field key used for worker lane tagging.
+const LaneKey = "lane"
+
+type laneCtxKey struct{}
+
+// ContextWithLane returns a context carrying the worker lane identifier.
+// Worker pools set it once per worker so that entries logged on behalf of
+// that worker can be followed even when interleaved with other lanes.
+func ContextWithLane(ctx context.Context, lane string) context.Context {
+	return context.WithValue(ctx, laneCtxKey{}, lane)
+}
+
+// LaneFromContext returns the lane set by ContextWithLane.
+func LaneFromContext(ctx context.Context) (string, bool) {
+	lane, ok := ctx.Value(laneCtxKey{}).(string)
+	return lane, ok
+}
+
+// WithLane returns a logger whose entries are tagged with the lane found in ctx.
+// The logger is returned unchanged when ctx carries no lane; a lane already set
+// by an earlier WithLane is replaced rather than repeated.
+func WithLane(ctx context.Context, lg *zap.Logger) *zap.Logger {
+	lane, ok := LaneFromContext(ctx)
+	if !ok {
+		return lg
+	}
+	return lg.WithOptions(zap.WrapCore(func(c zapcore.Core) zapcore.Core {
+		if lc, ok := c.(*laneCore); ok {
+			c = lc.Core
+		}
+		return NewLaneCore(c, lane)
+	}))
+}
+
+// NewLaneCore wraps core so that every entry carries the lane field as its last field.
+func NewLaneCore(core zapcore.Core, lane string) zapcore.Core {
+	return &laneCore{
+		Core:  core,
+		field: zap.String(LaneKey, lane),
+	}
+}
+
+type laneCore struct {
+	zapcore.Core
+	field zapcore.Field
+}
+
+func (c *laneCore) With(fields []zapcore.Field) zapcore.Core {
+	return &laneCore{
+		Core:  c.Core.With(fields),
+		field: c.field,
+	}
+}
+
+func (c *laneCore) Check(ent zapcore.Entry, ce *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	if c.Enabled(ent.Level) {
+		return ce.AddCore(ent, c)
+	}
+	return ce
+}
+
+func (c *laneCore) Write(ent zapcore.Entry, fields []zapcore.Field) error {
+	tagged := make([]zapcore.Field, 0, len(fields)+1)
+	tagged = append(tagged, fields...)
+	return c.Core.Write(ent, append(tagged, c.field))
+}
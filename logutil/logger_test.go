@@ -1,9 +1,11 @@
 package logutil
 
 import (
+	"context"
 	"testing"
 
 	"go.uber.org/zap"
+	"go.uber.org/zap/zaptest/observer"
 )
 
 func TestLogger(t *testing.T) {
@@ -24,3 +26,45 @@ func TestLogger(t *testing.T) {
 	// 	zap.Duration("time", time.Second),
 	// 	zap.Int("int", 16))
 }
+
+func TestLaneLogger(t *testing.T) {
+	core, logs := observer.New(zap.InfoLevel)
+	ctx := ContextWithLane(context.Background(), "worker-3")
+	lg := WithLane(ctx, zap.New(core).With(zap.String("pkg", "test")))
+	lg.Info("lane tagged", zap.Int("offset", 42))
+	lg.With(zap.String("topic", "t")).Info("after with")
+	WithLane(ContextWithLane(ctx, "worker-4"), lg).Info("relaned")
+
+	wantLanes := []string{"worker-3", "worker-3", "worker-4"}
+	entries := logs.AllUntimed()
+	if len(entries) != len(wantLanes) {
+		t.Fatalf("got %d entries", len(entries))
+	}
+	for i, e := range entries {
+		lanes := 0
+		for _, f := range e.Context {
+			if f.Key == LaneKey {
+				lanes++
+			}
+		}
+		if lanes != 1 {
+			t.Fatalf("entry %q has %d lane fields", e.Message, lanes)
+		}
+		if got := e.ContextMap()[LaneKey]; got != wantLanes[i] {
+			t.Fatalf("entry %q lane %v, want %s", e.Message, got, wantLanes[i])
+		}
+	}
+	if e := entries[1].ContextMap(); e["topic"] != "t" || e["pkg"] != "test" {
+		t.Fatalf("fields lost after With: %v", e)
+	}
+
+	direct := zap.New(NewLaneCore(core, "worker-5"))
+	direct.Info("direct")
+	if got := logs.AllUntimed()[3].ContextMap()[LaneKey]; got != "worker-5" {
+		t.Fatalf("NewLaneCore lane %v", got)
+	}
+
+	if WithLane(context.Background(), GetLogger()) != GetLogger() {
+		t.Fatal("logger without lane should be returned unchanged")
+	}
+}
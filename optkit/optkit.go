@@ -0,0 +1,47 @@
+package optkit
+
+// Option 函数选项，各包的XxxOption应定义为Option[Xxx]的别名，保证行为一致
+type Option[T any] func(*T)
+
+// Validator 选项应用完成后的校验钩子，由被配置的类型实现
+type Validator interface {
+	Validate() error
+}
+
+// ApplyTo 依次应用opts(nil选项被忽略)，t实现Validator时随后调用Validate
+func ApplyTo[T any](t *T, opts ...Option[T]) error {
+	for _, opt := range opts {
+		if opt != nil {
+			opt(t)
+		}
+	}
+	if v, ok := any(t).(Validator); ok {
+		return v.Validate()
+	}
+	return nil
+}
+
+// Apply 在defaults的副本上应用opts并校验，适用于普通配置结构体
+func Apply[T any](defaults T, opts ...Option[T]) (T, error) {
+	err := ApplyTo(&defaults, opts...)
+	return defaults, err
+}
+
+// Group 将多个选项组合为一个，便于复用一组常用配置
+func Group[T any](opts ...Option[T]) Option[T] {
+	return func(t *T) {
+		for _, opt := range opts {
+			if opt != nil {
+				opt(t)
+			}
+		}
+	}
+}
+
+// When cond为true时才应用opts
+func When[T any](cond bool, opts ...Option[T]) Option[T] {
+	if !cond {
+		return nil
+	}
+	return Group(opts...)
+}
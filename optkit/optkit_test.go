@@ -0,0 +1,83 @@
+package optkit
+
+import (
+	"errors"
+	"testing"
+)
+
+type server struct {
+	host string
+	port int
+	tls  bool
+}
+
+var errNoPort = errors.New("no port")
+
+func (s *server) Validate() error {
+	if s.port <= 0 {
+		return errNoPort
+	}
+	return nil
+}
+
+type plain struct {
+	n int
+}
+
+func withPort(port int) Option[server]    { return func(s *server) { s.port = port } }
+func withHost(host string) Option[server] { return func(s *server) { s.host = host } }
+func withTLS() Option[server]             { return func(s *server) { s.tls = true } }
+
+func TestApplyTo(t *testing.T) {
+	s := &server{}
+	if err := ApplyTo(s, withHost("a"), nil, withPort(80)); err != nil {
+		t.Fatal(err)
+	}
+	if s.host != "a" || s.port != 80 {
+		t.Fatalf("unexpected %+v", s)
+	}
+
+	// 选项应用完后才校验
+	if err := ApplyTo(&server{}, withHost("a")); !errors.Is(err, errNoPort) {
+		t.Fatalf("expected validate error, got %v", err)
+	}
+
+	// 未实现Validator的类型不校验
+	p := &plain{}
+	if err := ApplyTo(p, func(p *plain) { p.n = 3 }); err != nil || p.n != 3 {
+		t.Fatalf("plain %+v err %v", p, err)
+	}
+}
+
+func TestApply(t *testing.T) {
+	defaults := server{host: "localhost", port: 80}
+	s, err := Apply(defaults, withPort(443), withTLS())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s.host != "localhost" || s.port != 443 || !s.tls {
+		t.Fatalf("unexpected %+v", s)
+	}
+	if defaults.port != 80 || defaults.tls {
+		t.Fatal("defaults modified")
+	}
+	if _, err = Apply(defaults, withPort(0)); !errors.Is(err, errNoPort) {
+		t.Fatalf("expected validate error, got %v", err)
+	}
+}
+
+func TestGroupAndWhen(t *testing.T) {
+	secure := Group(withPort(443), nil, withTLS())
+	s, err := Apply(server{}, secure, withHost("a"))
+	if err != nil || s.port != 443 || !s.tls || s.host != "a" {
+		t.Fatalf("group %+v err %v", s, err)
+	}
+
+	if When(false, withTLS()) != nil {
+		t.Fatal("When(false) should return nil")
+	}
+	s, _ = Apply(server{port: 80}, When(false, withTLS()), When(true, withHost("b")))
+	if s.tls || s.host != "b" {
+		t.Fatalf("when %+v", s)
+	}
+}
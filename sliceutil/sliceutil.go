@@ -0,0 +1,82 @@
+package sliceutil
+
+// Chunk 按size切分s，各块共享s的底层数组，不复制元素
+func Chunk[S ~[]E, E any](s S, size int) []S {
+	if size <= 0 {
+		panic("chunk size must be positive")
+	}
+	chunks := make([]S, 0, (len(s)+size-1)/size)
+	for size < len(s) {
+		s, chunks = s[size:], append(chunks, s[:size:size])
+	}
+	if len(s) > 0 {
+		chunks = append(chunks, s)
+	}
+	return chunks
+}
+
+// Unique 按首次出现的顺序去重，返回新切片
+func Unique[S ~[]E, E comparable](s S) S {
+	seen := make(map[E]struct{}, len(s))
+	r := make(S, 0, len(s))
+	for _, v := range s {
+		if _, ok := seen[v]; !ok {
+			seen[v] = struct{}{}
+			r = append(r, v)
+		}
+	}
+	return r
+}
+
+// Filter 返回满足keep的元素组成的新切片
+func Filter[S ~[]E, E any](s S, keep func(E) bool) S {
+	r := make(S, 0, len(s))
+	for _, v := range s {
+		if keep(v) {
+			r = append(r, v)
+		}
+	}
+	return r
+}
+
+// FilterInPlace 同Filter，复用s的底层数组，调用后s不应再使用
+func FilterInPlace[S ~[]E, E any](s S, keep func(E) bool) S {
+	r := s[:0]
+	for _, v := range s {
+		if keep(v) {
+			r = append(r, v)
+		}
+	}
+	clear(s[len(r):])
+	return r
+}
+
+func Map[S ~[]E, E, R any](s S, f func(E) R) []R {
+	r := make([]R, len(s))
+	for i, v := range s {
+		r[i] = f(v)
+	}
+	return r
+}
+
+// GroupBy 按key分组，组内保持原顺序
+func GroupBy[S ~[]E, E any, K comparable](s S, key func(E) K) map[K]S {
+	groups := make(map[K]S)
+	for _, v := range s {
+		k := key(v)
+		groups[k] = append(groups[k], v)
+	}
+	return groups
+}
+
+// Partition 将s分为满足和不满足pred的两部分，均保持原顺序
+func Partition[S ~[]E, E any](s S, pred func(E) bool) (matched, rest S) {
+	for _, v := range s {
+		if pred(v) {
+			matched = append(matched, v)
+		} else {
+			rest = append(rest, v)
+		}
+	}
+	return
+}
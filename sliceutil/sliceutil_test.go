@@ -0,0 +1,59 @@
+package sliceutil
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestSliceUtil(t *testing.T) {
+	s := []int{1, 2, 3, 4, 5}
+	if got := Chunk(s, 2); !reflect.DeepEqual(got, [][]int{{1, 2}, {3, 4}, {5}}) {
+		t.Fatalf("chunk %v", got)
+	}
+	if got := Chunk(s, 2); cap(got[0]) != 2 {
+		t.Fatal("append to a chunk would overwrite the next one")
+	}
+	if got := Unique([]int{3, 1, 3, 2, 1}); !reflect.DeepEqual(got, []int{3, 1, 2}) {
+		t.Fatalf("unique %v", got)
+	}
+	even, odd := Partition(s, func(v int) bool { return v%2 == 0 })
+	if !reflect.DeepEqual(even, []int{2, 4}) || !reflect.DeepEqual(odd, []int{1, 3, 5}) {
+		t.Fatalf("partition %v %v", even, odd)
+	}
+	if got := GroupBy(s, func(v int) bool { return v > 2 }); len(got[true]) != 3 {
+		t.Fatalf("groupby %v", got)
+	}
+	if got := FilterInPlace([]int{1, 2, 3, 4}, func(v int) bool { return v > 2 }); !reflect.DeepEqual(got, []int{3, 4}) {
+		t.Fatalf("filter in place %v", got)
+	}
+}
+
+func BenchmarkUnique(b *testing.B) {
+	s := make([]int, 1024)
+	for i := range s {
+		s[i] = i % 100
+	}
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		Unique(s)
+	}
+}
+
+func BenchmarkFilter(b *testing.B) {
+	s := make([]int, 1024)
+	for i := range s {
+		s[i] = i
+	}
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		Filter(s, func(v int) bool { return v&1 == 0 })
+	}
+}
+
+func BenchmarkChunk(b *testing.B) {
+	s := make([]int, 1024)
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		Chunk(s, 100)
+	}
+}